@@ -0,0 +1,46 @@
+// Package clientkit combines the client-side pieces of the other middleware
+// packages into a single [http.Client] wrapper, stacked in the right order.
+package clientkit
+
+import (
+	"net/http"
+
+	"jsocol.io/middleware/deadline"
+	"jsocol.io/middleware/logging"
+)
+
+type config struct {
+	deadlineOpts []deadline.Option
+	loggingOpts  []logging.Option
+}
+
+// Option configures the layers added by [WrapClient].
+type Option func(*config)
+
+// WithDeadlineOptions passes options to the [deadline.Transport] layer.
+func WithDeadlineOptions(opts ...deadline.Option) Option {
+	return func(c *config) {
+		c.deadlineOpts = append(c.deadlineOpts, opts...)
+	}
+}
+
+// WithLoggingOptions passes options to the [logging.Transport] layer.
+func WithLoggingOptions(opts ...logging.Option) Option {
+	return func(c *config) {
+		c.loggingOpts = append(c.loggingOpts, opts...)
+	}
+}
+
+// WrapClient wraps the transport of c so that every outbound request carries a
+// deadline header and is logged, and returns c. The [deadline.Transport] is
+// the outer layer, so calls it rejects, e.g. with
+// [deadline.WithMinPropagatedBudget], are not logged as outbound requests.
+func WrapClient(c *http.Client, opts ...Option) *http.Client {
+	cfg := &config{}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	c = logging.WrapClient(c, cfg.loggingOpts...)
+	return deadline.WrapClient(c, cfg.deadlineOpts...)
+}
@@ -0,0 +1,90 @@
+package clientkit_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"jsocol.io/middleware/clientkit"
+	"jsocol.io/middleware/deadline"
+	"jsocol.io/middleware/logging"
+)
+
+type testRoundTripper struct {
+	req *http.Request
+}
+
+func (trt *testRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	trt.req = r
+	return &http.Response{StatusCode: http.StatusOK, Request: r}, nil
+}
+
+type testHandler struct {
+	records []slog.Record
+}
+
+func (t *testHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (t *testHandler) Handle(_ context.Context, rec slog.Record) error {
+	t.records = append(t.records, rec)
+	return nil
+}
+
+func (t *testHandler) WithAttrs([]slog.Attr) slog.Handler {
+	return t
+}
+
+func (t *testHandler) WithGroup(string) slog.Handler {
+	return t
+}
+
+func TestWrapClient(t *testing.T) {
+	th := &testHandler{}
+	trt := &testRoundTripper{}
+	timeout := 3 * time.Second
+
+	client := clientkit.WrapClient(
+		&http.Client{Transport: trt},
+		clientkit.WithDeadlineOptions(deadline.WithDefaultTimeout(timeout)),
+		clientkit.WithLoggingOptions(logging.WithLogger(slog.New(th))),
+	)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	_, err := client.Do(req)
+	assert.NoError(t, err)
+
+	dl := trt.req.Header.Get(deadline.DefaultHeaderName)
+	assert.NotEmpty(t, dl)
+	dlTime, err := time.Parse(time.RFC3339Nano, dl)
+	assert.NoError(t, err)
+	assert.InDelta(t, timeout, time.Until(dlTime), float64(5*time.Millisecond))
+
+	assert.Len(t, th.records, 1)
+	assert.Equal(t, "GET example.com/ [200]", th.records[0].Message)
+}
+
+func TestWrapClient_DeadlineIsOuterLayer(t *testing.T) {
+	th := &testHandler{}
+	trt := &testRoundTripper{}
+
+	client := clientkit.WrapClient(
+		&http.Client{Transport: trt},
+		clientkit.WithDeadlineOptions(deadline.WithMinPropagatedBudget(time.Second)),
+		clientkit.WithLoggingOptions(logging.WithLogger(slog.New(th))),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+	_, err := client.Do(req)
+
+	assert.ErrorIs(t, err, deadline.ErrBudgetTooLow)
+	assert.Nil(t, trt.req)
+	assert.Empty(t, th.records)
+}
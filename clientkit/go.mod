@@ -0,0 +1,20 @@
+module jsocol.io/middleware/clientkit
+
+go 1.24.5
+
+require (
+	github.com/stretchr/testify v1.10.0
+	jsocol.io/middleware/deadline v0.0.0-20261016153239-fd90a4a4ddd6
+	jsocol.io/middleware/logging v0.0.0-20261016153239-fd90a4a4ddd6
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	jsocol.io/middleware/deadline => ../deadline
+	jsocol.io/middleware/logging => ../logging
+)
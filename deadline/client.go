@@ -1,12 +1,21 @@
 package deadline
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 )
 
 var _ http.RoundTripper = &Transport{}
 
+// ErrBudgetTooLow is returned by the [Transport] for calls whose remaining
+// budget is below the minimum set with [WithMinPropagatedBudget]. The error
+// also wraps [context.DeadlineExceeded].
+var ErrBudgetTooLow = errors.New("deadline: remaining budget too low")
+
 type Transport struct {
 	http.RoundTripper
 
@@ -15,28 +24,59 @@ type Transport struct {
 
 func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
 	var deadline time.Time
+	var original bool
 	now := time.Now()
+	propagate := t.propagateMethods == nil || t.propagateMethods[method(r)]
 
-	if dl, ok := r.Context().Deadline(); ok {
+	if dl, ok := r.Context().Deadline(); ok && propagate {
 		deadline = dl
-	} else if t.defaultTimeout != 0 {
+		original = t.propagateOriginal
+		if remaining := dl.Sub(now); t.budgetFraction > 0 && remaining > 0 && !original {
+			deadline = now.Add(time.Duration(float64(remaining) * t.budgetFraction))
+		}
+	} else if t.defaultTimeout != 0 && propagate {
 		deadline = now.Add(t.defaultTimeout)
 	}
 
 	if !deadline.IsZero() {
-		if t.maxTimeout != 0 {
+		if t.maxTimeout != 0 && !original {
 			maxDeadline := now.Add(t.maxTimeout)
 			if deadline.After(maxDeadline) {
 				deadline = maxDeadline
 			}
 		}
 
+		if budget := deadline.Sub(now); t.minBudget > 0 && budget < t.minBudget {
+			if r.Body != nil {
+				r.Body.Close()
+			}
+			return nil, fmt.Errorf("%w: %v left, need %v: %w", ErrBudgetTooLow, max(budget, 0), t.minBudget, context.DeadlineExceeded)
+		}
+
 		r.Header.Add(t.headerName, deadline.Format(time.RFC3339Nano))
+		if t.relativeHeader != "" {
+			remaining := max(deadline.Sub(now), 0)
+			r.Header.Set(t.relativeHeader, strconv.FormatInt(remaining.Milliseconds(), 10))
+		}
+	}
+
+	if t.requestID != nil && t.requestIDHeader != "" {
+		if id := t.requestID(r.Context()); id != "" {
+			r.Header.Set(t.requestIDHeader, id)
+		}
 	}
 
 	return t.RoundTripper.RoundTrip(r)
 }
 
+// method returns the method of r, where the empty string means GET.
+func method(r *http.Request) string {
+	if r.Method == "" {
+		return http.MethodGet
+	}
+	return r.Method
+}
+
 func WrapClient(c *http.Client, opts ...Option) *http.Client {
 	t := &Transport{
 		RoundTripper: c.Transport,
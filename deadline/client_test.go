@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -103,3 +105,324 @@ func TestTransport_WithMaxTimeout(t *testing.T) {
 	assert.NoError(t, err)
 	assert.InDelta(t, maxTimeout, time.Until(dlTime), float64(5*time.Millisecond))
 }
+
+func TestTransport_WithPropagateOriginal(t *testing.T) {
+	maxTimeout := 2 * time.Second
+	ctxDeadline := time.Now().Add(5 * time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), ctxDeadline)
+	defer cancel()
+
+	trt := &testRoundTripper{}
+	client := &http.Client{
+		Transport: trt,
+	}
+	client = deadline.WrapClient(client, deadline.WithMaxTimeout(maxTimeout), deadline.WithPropagateOriginal())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	_, _ = client.Do(req)
+
+	dl := trt.req.Header.Get(deadline.DefaultHeaderName)
+	assert.Equal(t, ctxDeadline.Format(time.RFC3339Nano), dl)
+}
+
+func TestTransport_WithRelativeHeader(t *testing.T) {
+	timeout := 5 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	trt := &testRoundTripper{}
+	client := &http.Client{
+		Transport: trt,
+	}
+	client = deadline.WrapClient(client, deadline.WithRelativeHeader("Deadline-Ms"))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	_, _ = client.Do(req)
+
+	dlTime, err := time.Parse(time.RFC3339Nano, trt.req.Header.Get(deadline.DefaultHeaderName))
+	assert.NoError(t, err)
+
+	ms, err := strconv.ParseInt(trt.req.Header.Get("Deadline-Ms"), 10, 64)
+	assert.NoError(t, err)
+
+	assert.InDelta(t, time.Until(dlTime).Milliseconds(), ms, 5)
+	assert.InDelta(t, timeout.Milliseconds(), ms, 5)
+}
+
+func TestTransport_WithBudgetFraction(t *testing.T) {
+	f := func(fraction float64, opts []deadline.Option, expected time.Duration) func(*testing.T) {
+		return func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 4*time.Second)
+			defer cancel()
+
+			trt := &testRoundTripper{}
+			client := &http.Client{
+				Transport: trt,
+			}
+			client = deadline.WrapClient(client, append(opts, deadline.WithBudgetFraction(fraction))...)
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			_, _ = client.Do(req)
+
+			dlTime, err := time.Parse(time.RFC3339Nano, trt.req.Header.Get(deadline.DefaultHeaderName))
+			assert.NoError(t, err)
+			assert.InDelta(t, expected, time.Until(dlTime), float64(5*time.Millisecond))
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		fraction float64
+		opts     []deadline.Option
+		expected time.Duration
+	}{
+		{
+			name:     "half the budget",
+			fraction: 0.5,
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "clamped to the whole budget",
+			fraction: 2,
+			expected: 4 * time.Second,
+		},
+		{
+			name:     "non-positive fraction is ignored",
+			fraction: 0,
+			expected: 4 * time.Second,
+		},
+		{
+			name:     "bypassed when propagating the original",
+			fraction: 0.5,
+			opts:     []deadline.Option{deadline.WithPropagateOriginal()},
+			expected: 4 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.fraction, tc.opts, tc.expected))
+	}
+}
+
+type requestIDKey struct{}
+
+func TestTransport_WithRequestIDHeader(t *testing.T) {
+	f := func(id string) func(*testing.T) {
+		return func(t *testing.T) {
+			ctx := context.Background()
+			if id != "" {
+				ctx = context.WithValue(ctx, requestIDKey{}, id)
+			}
+
+			trt := &testRoundTripper{}
+			client := &http.Client{
+				Transport: trt,
+			}
+			client = deadline.WrapClient(client, deadline.WithRequestIDHeader("X-Request-Id", func(ctx context.Context) string {
+				id, _ := ctx.Value(requestIDKey{}).(string)
+				return id
+			}))
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			_, _ = client.Do(req)
+
+			assert.Equal(t, id, trt.req.Header.Get("X-Request-Id"))
+			assert.Equal(t, id != "", len(trt.req.Header.Values("X-Request-Id")) == 1)
+		}
+	}
+
+	testCases := []struct {
+		name string
+		id   string
+	}{
+		{
+			name: "request ID in context",
+			id:   "req-1234",
+		},
+		{
+			name: "no request ID",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.id))
+	}
+}
+
+func TestTransport_WithConnectTimeout(t *testing.T) {
+	f := func(value string, expected time.Duration) func(*testing.T) {
+		return func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			trt := &testRoundTripper{}
+			client := &http.Client{
+				Transport: trt,
+			}
+			client = deadline.WrapClient(client, deadline.WithConnectTimeout())
+			req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+			_, _ = client.Do(req)
+
+			sent := trt.req.Header.Get(deadline.ConnectTimeoutHeader)
+			ms, err := strconv.ParseInt(sent, 10, 64)
+			assert.NoError(t, err)
+			assert.InDelta(t, 3000, ms, 5)
+
+			// Forward only the Connect header, as a Connect client would.
+			if value == "" {
+				value = sent
+			}
+			var got time.Duration
+			hasDeadline := false
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if dl, ok := r.Context().Deadline(); ok {
+					hasDeadline = true
+					got = time.Until(dl)
+				}
+			})
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			r.Header.Set(deadline.ConnectTimeoutHeader, value)
+
+			deadline.Wrap(mux, deadline.WithConnectTimeout()).ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, expected != 0, hasDeadline)
+			assert.InDelta(t, expected, got, float64(10*time.Millisecond))
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{
+			name:     "round trip",
+			expected: 3 * time.Second,
+		},
+		{
+			name:  "malformed",
+			value: "soon",
+		},
+		{
+			name:  "negative",
+			value: "-100",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.value, tc.expected))
+	}
+}
+
+func TestTransport_WithMinPropagatedBudget(t *testing.T) {
+	f := func(timeout time.Duration, fails bool) func(*testing.T) {
+		return func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			trt := &testRoundTripper{}
+			client := &http.Client{
+				Transport: trt,
+			}
+			client = deadline.WrapClient(client, deadline.WithMinPropagatedBudget(100*time.Millisecond))
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			_, err := client.Do(req)
+
+			if fails {
+				assert.ErrorIs(t, err, deadline.ErrBudgetTooLow)
+				assert.ErrorIs(t, err, context.DeadlineExceeded)
+				assert.Nil(t, trt.req, "underlying round tripper called")
+			} else {
+				assert.NotErrorIs(t, err, deadline.ErrBudgetTooLow)
+				assert.NotNil(t, trt.req, "underlying round tripper called")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		timeout time.Duration
+		fails   bool
+	}{
+		{
+			name:    "tiny budget",
+			timeout: 10 * time.Millisecond,
+			fails:   true,
+		},
+		{
+			name:    "enough budget",
+			timeout: 5 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.timeout, tc.fails))
+	}
+}
+
+func TestTransport_WithPropagateMethods(t *testing.T) {
+	f := func(methods []string, method string, propagated bool) func(*testing.T) {
+		return func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			trt := &testRoundTripper{}
+			client := &http.Client{
+				Transport: trt,
+			}
+			client = deadline.WrapClient(client, deadline.WithPropagateMethods(methods...), deadline.WithRelativeHeader("Deadline-Ms"))
+			req, _ := http.NewRequestWithContext(ctx, method, "/", nil)
+			_, _ = client.Do(req)
+
+			assert.Equal(t, propagated, trt.req.Header.Get(deadline.DefaultHeaderName) != "")
+			assert.Equal(t, propagated, trt.req.Header.Get("Deadline-Ms") != "")
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		methods    []string
+		method     string
+		propagated bool
+	}{
+		{
+			name:       "listed method",
+			methods:    []string{http.MethodGet},
+			method:     http.MethodGet,
+			propagated: true,
+		},
+		{
+			name:    "unlisted method",
+			methods: []string{http.MethodGet},
+			method:  http.MethodPost,
+		},
+		{
+			name:       "no methods",
+			method:     http.MethodPost,
+			propagated: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.methods, tc.method, tc.propagated))
+	}
+}
+
+func TestTransport_WithPropagateMethods_RequestID(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), requestIDKey{}, "req-1234"), 5*time.Second)
+	defer cancel()
+
+	trt := &testRoundTripper{}
+	client := &http.Client{
+		Transport: trt,
+	}
+	client = deadline.WrapClient(client,
+		deadline.WithPropagateMethods(http.MethodGet),
+		deadline.WithRequestIDHeader("X-Request-Id", func(ctx context.Context) string {
+			id, _ := ctx.Value(requestIDKey{}).(string)
+			return id
+		}),
+	)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	_, _ = client.Do(req)
+
+	assert.Empty(t, trt.req.Header.Get(deadline.DefaultHeaderName))
+	assert.Equal(t, "req-1234", trt.req.Header.Get("X-Request-Id"))
+}
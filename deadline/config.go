@@ -1,13 +1,39 @@
 package deadline
 
-import "time"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"time"
+)
 
 const DefaultHeaderName = "Deadline"
 
+// ConnectTimeoutHeader is the header the Connect RPC protocol uses for the
+// timeout of a call, as a whole number of milliseconds.
+const ConnectTimeoutHeader = "Connect-Timeout-Ms"
+
 type config struct {
-	headerName     string
-	defaultTimeout time.Duration
-	maxTimeout     time.Duration
+	headerName        string
+	defaultTimeout    time.Duration
+	maxTimeout        time.Duration
+	softEnforce       func(*http.Request, time.Duration)
+	propagateOriginal bool
+	remainingHeader   string
+	earliestDeadline  bool
+	relativeHeader    string
+	budgetFraction    float64
+	routeSLA          map[string]time.Duration
+	requestIDHeader   string
+	requestID         func(context.Context) string
+	trailerDeadline   bool
+	sourceContext     bool
+	fallbackTimeout   time.Duration
+	minBudget         time.Duration
+	maxTimeoutFunc    func(*http.Request) time.Duration
+	propagateMethods  map[string]bool
 }
 
 func newConfig() *config {
@@ -16,6 +42,49 @@ func newConfig() *config {
 	}
 }
 
+// validate reports invalid or conflicting settings.
+func (c *config) validate() error {
+	var errs []error
+
+	if c.headerName == "" {
+		errs = append(errs, errors.New("deadline: header name is empty"))
+	}
+	if c.defaultTimeout < 0 {
+		errs = append(errs, fmt.Errorf("deadline: default timeout %v is negative", c.defaultTimeout))
+	}
+	if c.fallbackTimeout < 0 {
+		errs = append(errs, fmt.Errorf("deadline: fallback timeout %v is negative", c.fallbackTimeout))
+	}
+	if c.minBudget < 0 {
+		errs = append(errs, fmt.Errorf("deadline: min propagated budget %v is negative", c.minBudget))
+	}
+	if c.maxTimeout < 0 {
+		errs = append(errs, fmt.Errorf("deadline: max timeout %v is negative", c.maxTimeout))
+	}
+	if c.maxTimeout > 0 && c.defaultTimeout > c.maxTimeout {
+		errs = append(errs, fmt.Errorf("deadline: default timeout %v exceeds max timeout %v", c.defaultTimeout, c.maxTimeout))
+	}
+	if c.maxTimeout > 0 && c.fallbackTimeout > c.maxTimeout {
+		errs = append(errs, fmt.Errorf("deadline: fallback timeout %v exceeds max timeout %v", c.fallbackTimeout, c.maxTimeout))
+	}
+
+	for pattern, sla := range c.routeSLA {
+		if sla <= 0 {
+			errs = append(errs, fmt.Errorf("deadline: SLA %v for route %q is not positive", sla, pattern))
+		}
+	}
+
+	if c.relativeHeader != "" && http.CanonicalHeaderKey(c.relativeHeader) == http.CanonicalHeaderKey(c.headerName) {
+		errs = append(errs, fmt.Errorf("deadline: relative header %q is the same as the deadline header", c.relativeHeader))
+	}
+
+	if c.requestID != nil && c.requestIDHeader == "" {
+		errs = append(errs, errors.New("deadline: request ID header name is empty"))
+	}
+
+	return errors.Join(errs...)
+}
+
 type Option func(*config)
 
 func WithMaxTimeout(t time.Duration) Option {
@@ -35,3 +104,179 @@ func WithHeaderName(name string) Option {
 		c.headerName = name
 	}
 }
+
+// WithSoftEnforce reports, rather than enforces, requests whose handlers run
+// past their deadline. After the handler returns, fn is called with the
+// request and how far past the deadline it finished. The response is not
+// affected.
+func WithSoftEnforce(fn func(r *http.Request, over time.Duration)) Option {
+	return func(c *config) {
+		c.softEnforce = fn
+	}
+}
+
+// WithPropagateOriginal makes the [Transport] forward a deadline taken from
+// the request context exactly as it is, without applying the max timeout or
+// any other adjustment. This suits idempotent calls, where every retry in the
+// call tree should honor the same wall-clock cutoff, at the cost of giving
+// downstream calls no budget of their own to protect against a slow caller.
+// Deadlines created from a default timeout are still adjusted.
+func WithPropagateOriginal() Option {
+	return func(c *config) {
+		c.propagateOriginal = true
+	}
+}
+
+// WithRemainingHeader makes the [Middleware] set the named response header to
+// the whole number of milliseconds remaining until the request's deadline
+// when the handler started. Clients can use it to decide whether a retry is
+// worthwhile. Requests without a deadline do not get the header.
+func WithRemainingHeader(name string) Option {
+	return func(c *config) {
+		c.remainingHeader = name
+	}
+}
+
+// WithEarliestDeadline makes the [Middleware] consider every value of the
+// deadline header, including comma-separated values, and use the earliest.
+// This keeps a request that fans in from several upstreams from honoring a
+// relaxed deadline when a stricter one exists.
+func WithEarliestDeadline() Option {
+	return func(c *config) {
+		c.earliestDeadline = true
+	}
+}
+
+// WithRelativeHeader adds a second header carrying the deadline as a whole
+// number of milliseconds remaining, e.g. "Deadline-Ms", for peers that do not
+// understand the absolute format. The [Transport] sets both headers from the
+// same deadline, and the [Middleware] reads the relative header only when the
+// absolute one is missing or invalid.
+func WithRelativeHeader(name string) Option {
+	return func(c *config) {
+		c.relativeHeader = name
+	}
+}
+
+// WithBudgetFraction makes the [Transport] propagate only a fraction f of the
+// budget remaining on the request context, i.e. a deadline of now plus the
+// remaining time times f, reserving the rest for other calls or for
+// aggregating results. f is clamped to at most 1, and values of 0 or less
+// disable the option. Deadlines that have already passed are not adjusted.
+func WithBudgetFraction(f float64) Option {
+	return func(c *config) {
+		c.budgetFraction = min(f, 1)
+	}
+}
+
+// WithRouteSLA installs a deadline from a per-route SLA when the [Middleware]
+// wraps an [http.ServeMux]. slas maps a ServeMux pattern, e.g.
+// "GET /users/{id}", to the longest time requests to that route should take.
+// The SLA applies even when the request has no deadline header and there is
+// no default timeout, but never relaxes a stricter deadline from the header.
+// Routes not in slas are unaffected.
+func WithRouteSLA(slas map[string]time.Duration) Option {
+	return func(c *config) {
+		c.routeSLA = maps.Clone(slas)
+	}
+}
+
+// WithRequestIDHeader makes the [Transport] set the named header on outbound
+// requests to the request ID that fn reads from the request context, tying
+// the logs of the caller and the callee together. fn is usually the accessor
+// of whatever middleware assigned the ID to the incoming request. Nothing is
+// set when fn returns an empty string.
+func WithRequestIDHeader(name string, fn func(ctx context.Context) string) Option {
+	return func(c *config) {
+		c.requestIDHeader = name
+		c.requestID = fn
+	}
+}
+
+// WithTrailerDeadline makes the [Middleware] look for an updated deadline in
+// a request trailer of the same name as the deadline header, for streaming
+// requests that need to change their deadline mid-stream. The trailer is read
+// once the handler has consumed the whole body, and only tightens the
+// deadline. Since a context's deadline cannot change, the request context is
+// instead canceled when the new deadline passes, with
+// [context.DeadlineExceeded] as its [context.Cause]. Work that already looked
+// at the old deadline, e.g. to set its own timeout or to propagate it with the
+// [Transport], is unaffected, so only operations begun after the trailer is
+// read honor the new deadline. Clients must declare the trailer.
+func WithTrailerDeadline() Option {
+	return func(c *config) {
+		c.trailerDeadline = true
+	}
+}
+
+// WithConnectTimeout makes the deadline interoperate with the Connect RPC
+// protocol by using its [ConnectTimeoutHeader] as the relative header, as
+// with [WithRelativeHeader]. The [Transport] sets it alongside the deadline
+// header, and the [Middleware] reads it when the deadline header is missing.
+// Malformed values are ignored.
+func WithConnectTimeout() Option {
+	return WithRelativeHeader(ConnectTimeoutHeader)
+}
+
+// WithDeadlineSourceContext makes the [Middleware] record in the request
+// context where the deadline it installed came from, retrievable with
+// [SourceFromContext] and loggable with [SourceExtractor], to help debug
+// timeout configuration. The source is the last step that changed the
+// deadline, so e.g. a header deadline shortened to the max timeout is
+// [SourceMaxClamp].
+func WithDeadlineSourceContext() Option {
+	return func(c *config) {
+		c.sourceContext = true
+	}
+}
+
+// WithFallbackTimeout sets the timeout for requests without a deadline header
+// when the [Middleware] wraps a handler other than an [http.ServeMux], where
+// route-based options such as [WithRouteSLA] cannot apply. It takes
+// precedence over the default timeout for such handlers and is ignored for a
+// ServeMux. The max timeout still applies.
+func WithFallbackTimeout(t time.Duration) Option {
+	return func(c *config) {
+		c.fallbackTimeout = t
+	}
+}
+
+// WithMinPropagatedBudget makes the [Transport] fail calls whose budget, the
+// time until the deadline it would propagate, is less than d, without making
+// them, since they are unlikely to complete in time. The returned error
+// wraps both [ErrBudgetTooLow] and [context.DeadlineExceeded]. Calls without
+// a deadline are not affected.
+func WithMinPropagatedBudget(d time.Duration) Option {
+	return func(c *config) {
+		c.minBudget = d
+	}
+}
+
+// WithMaxTimeoutFunc makes the [Middleware] compute the max timeout for each
+// request with fn, in place of the static max timeout, e.g. to give routes or
+// customer tiers different maximums. A return value of 0 means no max.
+func WithMaxTimeoutFunc(fn func(r *http.Request) time.Duration) Option {
+	return func(c *config) {
+		c.maxTimeoutFunc = fn
+	}
+}
+
+// WithPropagateMethods makes the [Transport] propagate deadlines only for
+// requests with one of the given methods, e.g. only idempotent GETs, so that
+// other calls run to completion. Requests with other methods are sent
+// without the deadline headers, though other headers the Transport sets, such
+// as the request ID, still apply. Methods are case-sensitive, as in
+// [http.Request]. With no methods, deadlines are propagated for all requests,
+// as if the option was not given.
+func WithPropagateMethods(methods ...string) Option {
+	return func(c *config) {
+		if len(methods) == 0 {
+			c.propagateMethods = nil
+			return
+		}
+		c.propagateMethods = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			c.propagateMethods[m] = true
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package deadline
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type overrideKey struct{}
+
+// WithOverride returns a copy of ctx that makes the [Middleware] use timeout
+// for the request instead of the deadline header or default timeout, e.g. for
+// admin endpoints that need a longer budget. The precedence is override, then
+// header, then default timeout. An override is not limited by the max
+// timeout, but has no effect if the context already has a deadline.
+func WithOverride(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, overrideKey{}, timeout)
+}
+
+func overrideFromContext(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := ctx.Value(overrideKey{}).(time.Duration)
+	return timeout, ok
+}
+
+// Source describes where the deadline installed by the [Middleware] came
+// from.
+type Source string
+
+const (
+	// SourceHeader is a deadline from the deadline or relative header.
+	SourceHeader Source = "header"
+	// SourceDefault is a deadline from the default timeout.
+	SourceDefault Source = "default"
+	// SourceFallback is a deadline from the fallback timeout set with
+	// [WithFallbackTimeout].
+	SourceFallback Source = "fallback"
+	// SourceSLA is a deadline from a route SLA set with [WithRouteSLA].
+	SourceSLA Source = "sla"
+	// SourceMaxClamp is a deadline that was shortened to the max timeout.
+	SourceMaxClamp Source = "max_clamp"
+	// SourceOverride is a deadline from [WithOverride].
+	SourceOverride Source = "override"
+)
+
+type sourceKey struct{}
+
+// SourceFromContext returns the source of the deadline the [Middleware]
+// installed on ctx, if it was configured with [WithDeadlineSourceContext].
+func SourceFromContext(ctx context.Context) (Source, bool) {
+	source, ok := ctx.Value(sourceKey{}).(Source)
+	return source, ok
+}
+
+// SourceExtractor adds the source of the request's deadline, if known, as
+// deadline.source. It can be used as a
+// [jsocol.io/middleware/logging.ContextExtractor].
+func SourceExtractor(ctx context.Context) []slog.Attr {
+	source, ok := SourceFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []slog.Attr{slog.String("deadline.source", string(source))}
+}
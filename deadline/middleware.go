@@ -2,7 +2,10 @@ package deadline
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +18,25 @@ type Middleware struct {
 }
 
 func Wrap(target http.Handler, opts ...Option) http.Handler {
+	return newMiddleware(target, opts...)
+}
+
+// New is like [Wrap] but validates the options and returns an error
+// describing any invalid or conflicting configuration, so that mistakes can
+// fail fast at startup.
+func New(target http.Handler, opts ...Option) (http.Handler, error) {
+	if target == nil {
+		return nil, errors.New("deadline: target handler is nil")
+	}
+
+	mw := newMiddleware(target, opts...)
+	if err := mw.config.validate(); err != nil {
+		return nil, err
+	}
+	return mw, nil
+}
+
+func newMiddleware(target http.Handler, opts ...Option) *Middleware {
 	mw := &Middleware{
 		config: newConfig(),
 		target: target,
@@ -32,30 +54,128 @@ func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if _, ok := ctx.Deadline(); !ok {
 		var cancel context.CancelFunc
 		var deadline time.Time
+		var source Source
 		now := time.Now()
 
-		if incomingDeadline := r.Header.Get(m.headerName); incomingDeadline != "" {
-			if dl, err := time.Parse(time.RFC3339Nano, incomingDeadline); err == nil {
-				deadline = dl
-			}
+		if timeout, ok := overrideFromContext(ctx); ok {
+			deadline = now.Add(timeout)
+			source = SourceOverride
+		} else if deadline = m.headerDeadline(r); !deadline.IsZero() {
+			source = SourceHeader
+		}
+
+		if _, isMux := m.target.(*http.ServeMux); deadline.IsZero() && m.fallbackTimeout != 0 && !isMux {
+			deadline = now.Add(m.fallbackTimeout)
+			source = SourceFallback
 		}
 
 		if deadline.IsZero() && m.defaultTimeout != 0 {
 			deadline = now.Add(m.defaultTimeout)
+			source = SourceDefault
+		}
+
+		if source != SourceOverride {
+			if sla, ok := m.slaFor(r); ok {
+				if slaDeadline := now.Add(sla); deadline.IsZero() || slaDeadline.Before(deadline) {
+					deadline = slaDeadline
+					source = SourceSLA
+				}
+			}
 		}
 
 		if !deadline.IsZero() {
-			if m.maxTimeout != 0 {
-				maxDeadline := now.Add(m.maxTimeout)
+			maxTimeout := m.maxTimeout
+			if m.maxTimeoutFunc != nil {
+				maxTimeout = m.maxTimeoutFunc(r)
+			}
+			if maxTimeout != 0 && source != SourceOverride {
+				maxDeadline := now.Add(maxTimeout)
 				if deadline.After(maxDeadline) {
 					deadline = maxDeadline
+					source = SourceMaxClamp
 				}
 			}
 			ctx, cancel = context.WithDeadline(ctx, deadline)
 			defer cancel()
 
+			if m.sourceContext {
+				ctx = context.WithValue(ctx, sourceKey{}, source)
+			}
+
 			r = r.WithContext(ctx)
 		}
 	}
+
+	if m.trailerDeadline && r.Body != nil && r.Body != http.NoBody {
+		tb := &trailerBody{ReadCloser: r.Body, trailer: r.Trailer, name: m.headerName}
+		tb.ctx, tb.cancel = context.WithCancelCause(ctx)
+		defer tb.stop()
+
+		r = r.WithContext(tb.ctx)
+		r.Body = tb
+	}
+
+	if m.remainingHeader != "" {
+		if dl, ok := ctx.Deadline(); ok {
+			remaining := max(time.Until(dl), 0)
+			w.Header().Set(m.remainingHeader, strconv.FormatInt(remaining.Milliseconds(), 10))
+		}
+	}
+
 	m.target.ServeHTTP(w, r)
+
+	if m.softEnforce != nil {
+		if dl, ok := r.Context().Deadline(); ok {
+			if over := time.Since(dl); over > 0 {
+				m.softEnforce(r, over)
+			}
+		}
+	}
+}
+
+// slaFor returns the SLA configured with [WithRouteSLA] for the route that
+// r matches, if the target is an [http.ServeMux].
+func (m *Middleware) slaFor(r *http.Request) (time.Duration, bool) {
+	if len(m.routeSLA) == 0 {
+		return 0, false
+	}
+	mux, ok := m.target.(*http.ServeMux)
+	if !ok {
+		return 0, false
+	}
+	_, pattern := mux.Handler(r)
+	sla, ok := m.routeSLA[pattern]
+	return sla, ok
+}
+
+// headerDeadline parses the deadline header of r, falling back to the
+// relative header if one is configured. Invalid values are ignored.
+func (m *Middleware) headerDeadline(r *http.Request) time.Time {
+	deadline := m.absoluteDeadline(r)
+	if deadline.IsZero() && m.relativeHeader != "" {
+		if ms, err := strconv.ParseInt(r.Header.Get(m.relativeHeader), 10, 64); err == nil && ms >= 0 {
+			deadline = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+	}
+	return deadline
+}
+
+// absoluteDeadline parses the deadline header of r. With
+// [WithEarliestDeadline], every value of the header is considered.
+func (m *Middleware) absoluteDeadline(r *http.Request) time.Time {
+	if !m.earliestDeadline {
+		dl, _ := time.Parse(time.RFC3339Nano, r.Header.Get(m.headerName))
+		return dl
+	}
+
+	var deadline time.Time
+	for _, values := range r.Header.Values(m.headerName) {
+		for value := range strings.SplitSeq(values, ",") {
+			dl, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(value))
+			if err == nil && (deadline.IsZero() || dl.Before(deadline)) {
+				deadline = dl
+			}
+		}
+	}
+	return deadline
 }
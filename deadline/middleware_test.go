@@ -1,8 +1,13 @@
 package deadline_test
 
 import (
+	"context"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -86,3 +91,647 @@ func TestMiddleware_WithMaxTimeout(t *testing.T) {
 
 	assert.True(t, hasDeadline, "request context has deadline")
 }
+
+func TestMiddleware_WithSoftEnforce(t *testing.T) {
+	timeout := 10 * time.Millisecond
+	sleep := 30 * time.Millisecond
+	var called bool
+	var overage time.Duration
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(sleep)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	wrapped := deadline.Wrap(mux, deadline.WithDefaultTimeout(timeout), deadline.WithSoftEnforce(
+		func(_ *http.Request, over time.Duration) {
+			called = true
+			overage = over
+		},
+	))
+
+	wrapped.ServeHTTP(w, r)
+
+	assert.True(t, called, "soft enforcement callback called")
+	assert.GreaterOrEqual(t, overage, sleep-timeout)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestMiddleware_WithSoftEnforce_WithinDeadline(t *testing.T) {
+	called := false
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	wrapped := deadline.Wrap(mux, deadline.WithDefaultTimeout(time.Second), deadline.WithSoftEnforce(
+		func(*http.Request, time.Duration) {
+			called = true
+		},
+	))
+
+	wrapped.ServeHTTP(w, r)
+
+	assert.False(t, called, "soft enforcement callback not called")
+}
+
+func TestNew(t *testing.T) {
+	f := func(opts []deadline.Option, expectedErr string) func(*testing.T) {
+		return func(t *testing.T) {
+			h, err := deadline.New(http.NewServeMux(), opts...)
+			if expectedErr == "" {
+				assert.NoError(t, err)
+				assert.NotNil(t, h)
+			} else {
+				assert.ErrorContains(t, err, expectedErr)
+				assert.Nil(t, h)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name string
+		opts []deadline.Option
+		err  string
+	}{
+		{
+			name: "valid options",
+			opts: []deadline.Option{deadline.WithDefaultTimeout(time.Second), deadline.WithMaxTimeout(5 * time.Second)},
+		},
+		{
+			name: "negative default timeout",
+			opts: []deadline.Option{deadline.WithDefaultTimeout(-time.Second)},
+			err:  "default timeout -1s is negative",
+		},
+		{
+			name: "negative max timeout",
+			opts: []deadline.Option{deadline.WithMaxTimeout(-time.Second)},
+			err:  "max timeout -1s is negative",
+		},
+		{
+			name: "negative fallback timeout",
+			opts: []deadline.Option{deadline.WithFallbackTimeout(-time.Second)},
+			err:  "fallback timeout -1s is negative",
+		},
+		{
+			name: "negative min propagated budget",
+			opts: []deadline.Option{deadline.WithMinPropagatedBudget(-time.Second)},
+			err:  "min propagated budget -1s is negative",
+		},
+		{
+			name: "default exceeds max",
+			opts: []deadline.Option{deadline.WithDefaultTimeout(5 * time.Second), deadline.WithMaxTimeout(time.Second)},
+			err:  "default timeout 5s exceeds max timeout 1s",
+		},
+		{
+			name: "relative header same as deadline header",
+			opts: []deadline.Option{deadline.WithRelativeHeader("deadline")},
+			err:  `relative header "deadline" is the same as the deadline header`,
+		},
+		{
+			name: "empty header name",
+			opts: []deadline.Option{deadline.WithHeaderName("")},
+			err:  "header name is empty",
+		},
+		{
+			name: "non-positive route SLA",
+			opts: []deadline.Option{deadline.WithRouteSLA(map[string]time.Duration{"/": 0})},
+			err:  `SLA 0s for route "/" is not positive`,
+		},
+		{
+			name: "empty request ID header",
+			opts: []deadline.Option{deadline.WithRequestIDHeader("", func(context.Context) string { return "" })},
+			err:  "request ID header name is empty",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.opts, tc.err))
+	}
+}
+
+func TestMiddleware_WithOverride(t *testing.T) {
+	f := func(override time.Duration, header time.Time, expected time.Duration) func(*testing.T) {
+		return func(t *testing.T) {
+			hasDeadline := false
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if dl, ok := r.Context().Deadline(); ok {
+					hasDeadline = true
+					assert.InDelta(t, expected, time.Until(dl), float64(5*time.Millisecond))
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			ctx := context.Background()
+			if override != 0 {
+				ctx = deadline.WithOverride(ctx, override)
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+			if !header.IsZero() {
+				r.Header.Add(deadline.DefaultHeaderName, header.Format(time.RFC3339Nano))
+			}
+
+			wrapped := deadline.Wrap(mux, deadline.WithDefaultTimeout(time.Second), deadline.WithMaxTimeout(5*time.Second))
+
+			wrapped.ServeHTTP(w, r)
+
+			assert.True(t, hasDeadline, "request context has deadline")
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		override time.Duration
+		header   time.Time
+		expected time.Duration
+	}{
+		{
+			name:     "default timeout",
+			expected: time.Second,
+		},
+		{
+			name:     "header wins over default",
+			header:   time.Now().Add(2 * time.Second),
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "override wins over header",
+			override: 3 * time.Second,
+			header:   time.Now().Add(2 * time.Second),
+			expected: 3 * time.Second,
+		},
+		{
+			name:     "override is not limited by max timeout",
+			override: 10 * time.Second,
+			expected: 10 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.override, tc.header, tc.expected))
+	}
+}
+
+func TestMiddleware_WithRemainingHeader(t *testing.T) {
+	timeout := 3 * time.Second
+	header := "X-Deadline-Remaining-Ms"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	wrapped := deadline.Wrap(mux, deadline.WithDefaultTimeout(timeout), deadline.WithRemainingHeader(header))
+
+	wrapped.ServeHTTP(w, r)
+
+	remaining, err := strconv.ParseInt(w.Header().Get(header), 10, 64)
+	assert.NoError(t, err)
+	assert.InDelta(t, timeout.Milliseconds(), remaining, 5)
+}
+
+func TestMiddleware_WithEarliestDeadline(t *testing.T) {
+	earlier := time.Now().Add(2 * time.Second)
+	later := time.Now().Add(5 * time.Second)
+	latest := time.Now().Add(8 * time.Second)
+	hasDeadline := false
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if dl, ok := r.Context().Deadline(); ok {
+			hasDeadline = true
+			assert.Truef(t, earlier.Equal(dl), "got %v, want %v", dl, earlier)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add(deadline.DefaultHeaderName, later.Format(time.RFC3339Nano)+", "+earlier.Format(time.RFC3339Nano))
+	r.Header.Add(deadline.DefaultHeaderName, latest.Format(time.RFC3339Nano))
+	r.Header.Add(deadline.DefaultHeaderName, "not a deadline")
+
+	wrapped := deadline.Wrap(mux, deadline.WithEarliestDeadline())
+
+	wrapped.ServeHTTP(w, r)
+
+	assert.True(t, hasDeadline, "request context has deadline")
+}
+
+func TestMiddleware_WithRelativeHeader(t *testing.T) {
+	f := func(absolute time.Time, relative string, expected time.Duration) func(*testing.T) {
+		return func(t *testing.T) {
+			hasDeadline := false
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if dl, ok := r.Context().Deadline(); ok {
+					hasDeadline = true
+					assert.InDelta(t, expected, time.Until(dl), float64(5*time.Millisecond))
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if !absolute.IsZero() {
+				r.Header.Add(deadline.DefaultHeaderName, absolute.Format(time.RFC3339Nano))
+			}
+			r.Header.Add("Deadline-Ms", relative)
+
+			wrapped := deadline.Wrap(mux, deadline.WithRelativeHeader("Deadline-Ms"))
+
+			wrapped.ServeHTTP(w, r)
+
+			assert.True(t, hasDeadline, "request context has deadline")
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		absolute time.Time
+		relative string
+		expected time.Duration
+	}{
+		{
+			name:     "relative header only",
+			relative: "3000",
+			expected: 3 * time.Second,
+		},
+		{
+			name:     "absolute header preferred",
+			absolute: time.Now().Add(2 * time.Second),
+			relative: "3000",
+			expected: 2 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.absolute, tc.relative, tc.expected))
+	}
+}
+
+func TestMiddleware_WithRouteSLA(t *testing.T) {
+	f := func(path string, header time.Time, expected time.Duration) func(*testing.T) {
+		return func(t *testing.T) {
+			hasDeadline := false
+
+			mux := http.NewServeMux()
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				if dl, ok := r.Context().Deadline(); ok {
+					hasDeadline = true
+					assert.InDelta(t, expected, time.Until(dl), float64(5*time.Millisecond))
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}
+			mux.HandleFunc("GET /slow/{id}", handler)
+			mux.HandleFunc("/", handler)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			if !header.IsZero() {
+				r.Header.Add(deadline.DefaultHeaderName, header.Format(time.RFC3339Nano))
+			}
+
+			wrapped := deadline.Wrap(mux, deadline.WithRouteSLA(map[string]time.Duration{
+				"GET /slow/{id}": time.Second,
+			}))
+
+			wrapped.ServeHTTP(w, r)
+
+			assert.Equal(t, expected != 0, hasDeadline, "request context has deadline")
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		header   time.Time
+		expected time.Duration
+	}{
+		{
+			name:     "route with SLA",
+			path:     "/slow/1",
+			expected: time.Second,
+		},
+		{
+			name: "unlisted route",
+			path: "/other",
+		},
+		{
+			name:     "stricter header deadline",
+			path:     "/slow/1",
+			header:   time.Now().Add(500 * time.Millisecond),
+			expected: 500 * time.Millisecond,
+		},
+		{
+			name:     "relaxed header deadline",
+			path:     "/slow/1",
+			header:   time.Now().Add(5 * time.Second),
+			expected: time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.path, tc.header, tc.expected))
+	}
+}
+
+func TestMiddleware_WithTrailerDeadline(t *testing.T) {
+	f := func(header, trailer time.Duration, tightened bool) func(*testing.T) {
+		return func(t *testing.T) {
+			var cause error
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				select {
+				case <-r.Context().Done():
+					cause = context.Cause(r.Context())
+				case <-time.After(200 * time.Millisecond):
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("stream"))
+			if header != 0 {
+				r.Header.Set(deadline.DefaultHeaderName, time.Now().Add(header).Format(time.RFC3339Nano))
+			}
+			r.Trailer = http.Header{deadline.DefaultHeaderName: nil}
+			if trailer != 0 {
+				r.Trailer.Set(deadline.DefaultHeaderName, time.Now().Add(trailer).Format(time.RFC3339Nano))
+			}
+
+			wrapped := deadline.Wrap(mux, deadline.WithTrailerDeadline())
+
+			wrapped.ServeHTTP(w, r)
+
+			if tightened {
+				assert.ErrorIs(t, cause, context.DeadlineExceeded)
+			} else {
+				assert.NoError(t, cause)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		header    time.Duration
+		trailer   time.Duration
+		tightened bool
+	}{
+		{
+			name:      "trailer deadline",
+			trailer:   20 * time.Millisecond,
+			tightened: true,
+		},
+		{
+			name:      "trailer earlier than header",
+			header:    5 * time.Second,
+			trailer:   20 * time.Millisecond,
+			tightened: true,
+		},
+		{
+			name:    "trailer later than header",
+			header:  5 * time.Second,
+			trailer: 10 * time.Second,
+		},
+		{
+			name: "no trailer value",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.header, tc.trailer, tc.tightened))
+	}
+}
+
+func TestMiddleware_WithDeadlineSourceContext(t *testing.T) {
+	f := func(opts []deadline.Option, header time.Duration, override time.Duration, expected deadline.Source) func(*testing.T) {
+		return func(t *testing.T) {
+			var source deadline.Source
+			var attrs []slog.Attr
+			hasSource := false
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+				source, hasSource = deadline.SourceFromContext(r.Context())
+				attrs = deadline.SourceExtractor(r.Context())
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if header != 0 {
+				r.Header.Set(deadline.DefaultHeaderName, time.Now().Add(header).Format(time.RFC3339Nano))
+			}
+			if override != 0 {
+				r = r.WithContext(deadline.WithOverride(r.Context(), override))
+			}
+
+			wrapped := deadline.Wrap(mux, append(opts, deadline.WithDeadlineSourceContext())...)
+
+			wrapped.ServeHTTP(w, r)
+
+			if expected == "" {
+				assert.False(t, hasSource)
+				assert.Empty(t, attrs)
+				return
+			}
+			assert.True(t, hasSource)
+			assert.Equal(t, expected, source)
+			assert.Equal(t, []slog.Attr{slog.String("deadline.source", string(expected))}, attrs)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []deadline.Option
+		header   time.Duration
+		override time.Duration
+		expected deadline.Source
+	}{
+		{
+			name:     "header",
+			header:   time.Second,
+			expected: deadline.SourceHeader,
+		},
+		{
+			name:     "default",
+			opts:     []deadline.Option{deadline.WithDefaultTimeout(time.Second)},
+			expected: deadline.SourceDefault,
+		},
+		{
+			name:     "route SLA",
+			opts:     []deadline.Option{deadline.WithRouteSLA(map[string]time.Duration{"GET /": time.Second})},
+			header:   5 * time.Second,
+			expected: deadline.SourceSLA,
+		},
+		{
+			name:     "max clamp",
+			opts:     []deadline.Option{deadline.WithMaxTimeout(time.Second)},
+			header:   5 * time.Second,
+			expected: deadline.SourceMaxClamp,
+		},
+		{
+			name:     "override",
+			opts:     []deadline.Option{deadline.WithMaxTimeout(time.Second)},
+			override: 5 * time.Second,
+			expected: deadline.SourceOverride,
+		},
+		{
+			name: "no deadline",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.opts, tc.header, tc.override, tc.expected))
+	}
+}
+
+func TestMiddleware_WithFallbackTimeout(t *testing.T) {
+	f := func(mux bool, opts []deadline.Option, expected time.Duration) func(*testing.T) {
+		return func(t *testing.T) {
+			hasDeadline := false
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if dl, ok := r.Context().Deadline(); ok {
+					hasDeadline = true
+					assert.InDelta(t, expected, time.Until(dl), float64(5*time.Millisecond))
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			var target http.Handler = handler
+			if mux {
+				m := http.NewServeMux()
+				m.Handle("/", handler)
+				target = m
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			wrapped := deadline.Wrap(target, append(opts, deadline.WithFallbackTimeout(2*time.Second))...)
+
+			wrapped.ServeHTTP(w, r)
+
+			assert.Equal(t, expected != 0, hasDeadline, "request context has deadline")
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		mux      bool
+		opts     []deadline.Option
+		expected time.Duration
+	}{
+		{
+			name:     "non-mux handler",
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "non-mux handler with default",
+			opts:     []deadline.Option{deadline.WithDefaultTimeout(5 * time.Second)},
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "non-mux handler with max",
+			opts:     []deadline.Option{deadline.WithMaxTimeout(time.Second)},
+			expected: time.Second,
+		},
+		{
+			name: "mux ignores fallback",
+			mux:  true,
+		},
+		{
+			name:     "mux uses default",
+			mux:      true,
+			opts:     []deadline.Option{deadline.WithDefaultTimeout(5 * time.Second)},
+			expected: 5 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.mux, tc.opts, tc.expected))
+	}
+}
+
+func TestMiddleware_WithMaxTimeoutFunc(t *testing.T) {
+	f := func(tier string, expected time.Duration) func(*testing.T) {
+		return func(t *testing.T) {
+			hasDeadline := false
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if dl, ok := r.Context().Deadline(); ok {
+					hasDeadline = true
+					assert.InDelta(t, expected, time.Until(dl), float64(5*time.Millisecond))
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/report", nil)
+			r.Header.Set(deadline.DefaultHeaderName, time.Now().Add(time.Minute).Format(time.RFC3339Nano))
+			r.Header.Set("X-Tier", tier)
+
+			wrapped := deadline.Wrap(mux, deadline.WithMaxTimeout(time.Second), deadline.WithMaxTimeoutFunc(func(r *http.Request) time.Duration {
+				switch r.Header.Get("X-Tier") {
+				case "premium":
+					return 10 * time.Second
+				case "unlimited":
+					return 0
+				default:
+					return 2 * time.Second
+				}
+			}))
+
+			wrapped.ServeHTTP(w, r)
+
+			assert.True(t, hasDeadline, "request context has deadline")
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		tier     string
+		expected time.Duration
+	}{
+		{
+			name:     "free tier",
+			tier:     "free",
+			expected: 2 * time.Second,
+		},
+		{
+			name:     "premium tier",
+			tier:     "premium",
+			expected: 10 * time.Second,
+		},
+		{
+			name:     "no max",
+			tier:     "unlimited",
+			expected: time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.tier, tc.expected))
+	}
+}
@@ -0,0 +1,53 @@
+package deadline
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// trailerBody wraps a request body to look for an updated deadline in the
+// request trailers once the body has been read to the end.
+type trailerBody struct {
+	io.ReadCloser
+
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	trailer http.Header
+	name    string
+	done    bool
+	timer   *time.Timer
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF && !b.done {
+		b.done = true
+		b.tighten()
+	}
+	return n, err
+}
+
+// tighten cancels the context with [context.DeadlineExceeded] as the cause
+// when the deadline in the trailer passes, if it is earlier than the one the
+// context already has.
+func (b *trailerBody) tighten() {
+	dl, err := time.Parse(time.RFC3339Nano, b.trailer.Get(b.name))
+	if err != nil {
+		return
+	}
+	if current, ok := b.ctx.Deadline(); ok && !dl.Before(current) {
+		return
+	}
+	b.timer = time.AfterFunc(time.Until(dl), func() {
+		b.cancel(context.DeadlineExceeded)
+	})
+}
+
+func (b *trailerBody) stop() {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.cancel(nil)
+}
@@ -0,0 +1,142 @@
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"time"
+)
+
+// body wraps a request body to observe how the handler consumes it.
+type body struct {
+	io.ReadCloser
+	firstRead time.Time
+	bytes     int64
+	hash      hash.Hash
+
+	// sniff holds the first bytes read, up to sniffLen, if sniffing is set.
+	sniffing bool
+	sniff    []byte
+
+	capture *capture
+}
+
+// sniffLen is the number of bytes [http.DetectContentType] considers.
+const sniffLen = 512
+
+func (b *body) Read(p []byte) (int, error) {
+	if b.firstRead.IsZero() {
+		b.firstRead = time.Now()
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	if b.hash != nil {
+		b.hash.Write(p[:n])
+	}
+	if b.sniffing && len(b.sniff) < sniffLen {
+		b.sniff = append(b.sniff, p[:min(n, sniffLen-len(b.sniff))]...)
+	}
+	if b.capture != nil {
+		b.capture.write(p[:n])
+	}
+	return n, err
+}
+
+// capture keeps up to max bytes of a request or response body for
+// [WithBodyCaptureSampling].
+type capture struct {
+	max       int
+	buf       []byte
+	truncated bool
+}
+
+func (c *capture) write(p []byte) {
+	if room := max(c.max-len(c.buf), 0); len(p) > room {
+		p = p[:room]
+		c.truncated = true
+	}
+	c.buf = append(c.buf, p...)
+}
+
+// String returns the captured bytes, marked with a trailing ellipsis if the
+// body was longer.
+func (c *capture) String() string {
+	if c.truncated {
+		return string(c.buf) + "…"
+	}
+	return string(c.buf)
+}
+
+// sum returns the hex-encoded hash of the body read so far, truncated to
+// size bytes.
+func (b *body) sum(size int) string {
+	sum := b.hash.Sum(nil)
+	if size > 0 && size < len(sum) {
+		sum = sum[:size]
+	}
+	return hex.EncodeToString(sum)
+}
+
+// wrapsBody reports whether any option needs to observe the request body.
+func (m *Middleware) wrapsBody() bool {
+	return m.bodyFirstRead || m.largeBody > 0 || m.bodyHash != nil || m.sniffType || m.chunkedSize
+}
+
+// WithLargeBodyThreshold flags requests whose handler read more than n bytes
+// of the request body with http.large_body=true. Unlike a body limit, the
+// request is not rejected; only the bytes actually consumed by the handler
+// are counted.
+func WithLargeBodyThreshold(n int64) Option {
+	return func(mw *Middleware) {
+		mw.largeBody = n
+	}
+}
+
+// DefaultBodyHashSize is the number of bytes of the body hash logged by
+// [WithBodyHash] when no size is given.
+const DefaultBodyHashSize = 16
+
+// WithBodyHash logs a hash of the request body as http.body_hash, to spot
+// duplicate submissions without logging the body itself. Only the bytes the
+// handler reads are hashed, and requests whose body is not read get no
+// attribute. newHash creates the hash, SHA-256 if nil, and the hash is
+// truncated to size bytes, [DefaultBodyHashSize] if size is 0 or less, before
+// being hex-encoded.
+func WithBodyHash(newHash func() hash.Hash, size int) Option {
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	if size <= 0 {
+		size = DefaultBodyHashSize
+	}
+	return func(mw *Middleware) {
+		mw.bodyHash = newHash
+		mw.bodyHashSize = size
+	}
+}
+
+// WithSniffContentType logs the content type of request bodies that lack a
+// Content-Type header, as detected by [http.DetectContentType], as
+// http.request.sniffed_content_type. Only the bytes the handler reads are
+// examined, so the body is left intact, and requests whose body is not read
+// get no attribute.
+func WithSniffContentType() Option {
+	return func(mw *Middleware) {
+		mw.sniffType = true
+	}
+}
+
+// WithBodyCaptureSampling logs the request and response bodies of a random
+// fraction rate of requests, between 0 and 1, as http.request_body and
+// http.response_body, for debugging tricky clients. Only the bytes the
+// handler reads or writes are captured, up to maxBytes of each body, and
+// longer bodies are marked with a trailing ellipsis. Bodies may contain
+// personal data, so keep the rate low. The bodies seen by the handler and
+// the client are not changed. A maxBytes of 0 or less disables capturing.
+func WithBodyCaptureSampling(rate float64, maxBytes int) Option {
+	return func(mw *Middleware) {
+		mw.captureRate = rate
+		mw.captureMax = maxBytes
+	}
+}
@@ -0,0 +1,520 @@
+package logging_test
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+func TestMiddleware_WithBodyFirstRead(t *testing.T) {
+	f := func(delay time.Duration, read bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			var got string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(delay)
+				if read {
+					data, _ := io.ReadAll(r.Body)
+					got = string(data)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("payload"))
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithBodyFirstRead())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if read {
+				assert.Equal(t, "payload", got)
+				assert.GreaterOrEqual(t, attrs["http.body_first_read"].Value.Duration(), delay)
+			} else {
+				assert.NotContains(t, attrs, "http.body_first_read")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name  string
+		delay time.Duration
+		read  bool
+	}{
+		{
+			name:  "delayed read",
+			delay: 20 * time.Millisecond,
+			read:  true,
+		},
+		{
+			name: "body never read",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.delay, tc.read))
+	}
+}
+
+func TestMiddleware_WithTimingGroup(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("payload"))
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithBodyFirstRead(), logging.WithTimingGroup("timing"))
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.NotContains(t, attrs, "duration")
+	assert.NotContains(t, attrs, "http.body_first_read")
+
+	timing := make(map[string]slog.Attr)
+	for _, a := range attrs["timing"].Value.Group() {
+		timing[a.Key] = a
+	}
+	assert.Len(t, timing, 2)
+	assert.NotEqual(t, time.Duration(0), timing["duration"].Value.Duration())
+	assert.Contains(t, timing, "http.body_first_read")
+}
+
+func TestMiddleware_WithLargeBodyThreshold(t *testing.T) {
+	f := func(payload string, flagged bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.Copy(io.Discard, r.Body)
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithLargeBodyThreshold(8))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Equal(t, http.StatusNoContent, rr.Code)
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if flagged {
+				assert.True(t, attrs["http.large_body"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "http.large_body")
+			}
+			assert.NotContains(t, attrs, "http.body_first_read")
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		payload string
+		flagged bool
+	}{
+		{
+			name:    "smaller",
+			payload: "small",
+		},
+		{
+			name:    "equal",
+			payload: "eightbyt",
+		},
+		{
+			name:    "larger",
+			payload: "a much larger payload",
+			flagged: true,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.payload, tc.flagged))
+	}
+}
+
+func TestMiddleware_WithBodyHash(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	var got []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		got = append(got, string(data))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/unread", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithBodyHash(nil, 0))
+
+	for _, req := range []struct{ path, payload string }{
+		{"/", "payload"},
+		{"/", "payload"},
+		{"/", "other payload"},
+		{"/unread", "payload"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, req.path, strings.NewReader(req.payload))
+		mw.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	assert.Equal(t, []string{"payload", "payload", "other payload"}, got)
+	assert.Len(t, th.records, 4)
+	var hashes []string
+	for _, rec := range th.records {
+		var hash string
+		rec.Attrs(func(a slog.Attr) bool {
+			if a.Key == "http.body_hash" {
+				hash = a.Value.String()
+			}
+			return true
+		})
+		hashes = append(hashes, hash)
+	}
+	sum := sha256.Sum256([]byte("payload"))
+	assert.Equal(t, hex.EncodeToString(sum[:logging.DefaultBodyHashSize]), hashes[0])
+	assert.Equal(t, hashes[0], hashes[1])
+	assert.NotEqual(t, hashes[0], hashes[2])
+	assert.Empty(t, hashes[3])
+}
+
+func TestMiddleware_WithBodyHash_Configured(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithBodyHash(sha1.New, 4))
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("payload"))
+	mw.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	sum := sha1.Sum([]byte("payload"))
+	assert.Equal(t, hex.EncodeToString(sum[:4]), attrs["http.body_hash"].Value.String())
+}
+
+func TestMiddleware_WithSniffContentType(t *testing.T) {
+	f := func(contentType, payload string, read bool, expected string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			var got string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if read {
+					data, _ := io.ReadAll(r.Body)
+					got = string(data)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+			if contentType != "" {
+				r.Header.Set("Content-Type", contentType)
+			}
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithSniffContentType())
+
+			mw.ServeHTTP(rr, r)
+
+			if read {
+				assert.Equal(t, payload, got)
+			}
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if expected != "" {
+				assert.Equal(t, expected, attrs["http.request.sniffed_content_type"].Value.String())
+			} else {
+				assert.NotContains(t, attrs, "http.request.sniffed_content_type")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		contentType string
+		payload     string
+		read        bool
+		expected    string
+	}{
+		{
+			name:     "html without content type",
+			payload:  "<!DOCTYPE html><html><body>hi</body></html>",
+			read:     true,
+			expected: "text/html; charset=utf-8",
+		},
+		{
+			name:     "large body without content type",
+			payload:  "%PDF-" + strings.Repeat("x", 2048),
+			read:     true,
+			expected: "application/pdf",
+		},
+		{
+			name:        "content type present",
+			contentType: "application/json",
+			payload:     `{"a":1}`,
+			read:        true,
+		},
+		{
+			name:    "body not read",
+			payload: "<html></html>",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.contentType, tc.payload, tc.read, tc.expected))
+	}
+}
+
+func TestMiddleware_WithBodyCaptureSampling(t *testing.T) {
+	f := func(rate float64, maxBytes int, reqBody, respBody string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			var got string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				got = string(data)
+				_, _ = w.Write([]byte("response body"))
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("request body"))
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithBodyCaptureSampling(rate, maxBytes))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Equal(t, "request body", got)
+			assert.Equal(t, "response body", rr.Body.String())
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if reqBody != "" {
+				assert.Equal(t, reqBody, attrs["http.request_body"].Value.String())
+				assert.Equal(t, respBody, attrs["http.response_body"].Value.String())
+			} else {
+				assert.NotContains(t, attrs, "http.request_body")
+				assert.NotContains(t, attrs, "http.response_body")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		rate     float64
+		maxBytes int
+		reqBody  string
+		respBody string
+	}{
+		{
+			name:     "sampled",
+			rate:     1,
+			maxBytes: 100,
+			reqBody:  "request body",
+			respBody: "response body",
+		},
+		{
+			name:     "sampled and truncated",
+			rate:     1,
+			maxBytes: 7,
+			reqBody:  "request…",
+			respBody: "respons…",
+		},
+		{
+			name:     "not sampled",
+			rate:     math.SmallestNonzeroFloat64,
+			maxBytes: 100,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.rate, tc.maxBytes, tc.reqBody, tc.respBody))
+	}
+}
+
+func TestMiddleware_RequestSize(t *testing.T) {
+	f := func(chunked, read bool, opts []logging.Option, expected int64, logged bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			var got string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if read {
+					data, _ := io.ReadAll(r.Body)
+					got = string(data)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("payload"))
+			if chunked {
+				r.ContentLength = -1
+			}
+
+			mw := logging.Wrap(mux, append(opts, logging.WithLogger(logger))...)
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Equal(t, http.StatusNoContent, rr.Code)
+			if read {
+				assert.Equal(t, "payload", got)
+			}
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if logged {
+				assert.Equal(t, expected, attrs["http.request_size"].Value.Int64())
+			} else {
+				assert.NotContains(t, attrs, "http.request_size")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		chunked  bool
+		read     bool
+		opts     []logging.Option
+		expected int64
+		logged   bool
+	}{
+		{
+			name:     "known length",
+			read:     true,
+			expected: 7,
+			logged:   true,
+		},
+		{
+			name:     "known length not read",
+			expected: 7,
+			logged:   true,
+		},
+		{
+			name:    "chunked",
+			chunked: true,
+			read:    true,
+		},
+		{
+			name:     "chunked and counted",
+			chunked:  true,
+			read:     true,
+			opts:     []logging.Option{logging.WithChunkedRequestSize()},
+			expected: 7,
+			logged:   true,
+		},
+		{
+			name:    "chunked and counted but not read",
+			chunked: true,
+			opts:    []logging.Option{logging.WithChunkedRequestSize()},
+			logged:  true,
+		},
+		{
+			name: "disabled",
+			read: true,
+			opts: []logging.Option{logging.WithoutRequestSize()},
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.chunked, tc.read, tc.opts, tc.expected, tc.logged))
+	}
+}
+
+func TestMiddleware_WithBodyCaptureSampling_NonPositiveSize(t *testing.T) {
+	f := func(maxBytes int) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.ReadAll(r.Body)
+				_, _ = w.Write([]byte("response body"))
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("request body"))
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithBodyCaptureSampling(1, maxBytes))
+
+			assert.NotPanics(t, func() {
+				mw.ServeHTTP(httptest.NewRecorder(), r)
+			})
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.NotContains(t, attrs, "http.request_body")
+			assert.NotContains(t, attrs, "http.response_body")
+		}
+	}
+
+	t.Run("zero", f(0))
+	t.Run("negative", f(-1))
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+type stateKey struct{}
+
+type connKey struct{}
+
+// requestState is shared through the request context between the
+// [Middleware] and the handlers it wraps, so that handlers can report
+// information back to the access log.
+type requestState struct {
+	route       string
+	handlerName string
+	connSeq     uint64
+}
+
+// newRequestState returns the state for a request with context ctx, and a
+// copy of ctx carrying it.
+func newRequestState(ctx context.Context) (*requestState, context.Context) {
+	state := &requestState{}
+	if outer := stateFromContext(ctx); outer != nil {
+		// The request was already counted and routed by an outer
+		// Middleware. Its route stands unless this one resolves its own.
+		state.route = outer.route
+		state.handlerName = outer.handlerName
+		state.connSeq = outer.connSeq
+	} else if requests, ok := ctx.Value(connKey{}).(*atomic.Uint64); ok {
+		state.connSeq = requests.Add(1)
+	}
+	return state, contextWithState(ctx, state)
+}
+
+func contextWithState(ctx context.Context, state *requestState) context.Context {
+	return context.WithValue(ctx, stateKey{}, state)
+}
+
+func stateFromContext(ctx context.Context) *requestState {
+	state, _ := ctx.Value(stateKey{}).(*requestState)
+	return state
+}
+
+// RouteFromContext returns the [http.ServeMux] pattern that the [Middleware]
+// matched for the request, e.g. for use in metrics or authorization by
+// handlers and nested middleware. A nested Middleware without a router of its
+// own keeps the route matched by the outer one.
+func RouteFromContext(ctx context.Context) (string, bool) {
+	if state := stateFromContext(ctx); state != nil && state.route != "" {
+		return state.route, true
+	}
+	return "", false
+}
+
+// Named wraps h so that requests it handles are logged with a handler.name
+// attribute set to name. This gives an explicit name to handlers that are not
+// otherwise distinguishable by route.
+func Named(name string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if state := stateFromContext(r.Context()); state != nil {
+			state.handlerName = name
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// ConnContext stamps a per-connection request counter in ctx. Install it as
+// [http.Server.ConnContext] so that the [Middleware] numbers the requests on
+// each connection, and add [ConnRequestSeqExtractor] to log the number:
+//
+//	srv := &http.Server{
+//		Handler:     logging.Wrap(mux, logging.WithContextExtractors(logging.ConnRequestSeqExtractor)),
+//		ConnContext: logging.ConnContext,
+//	}
+func ConnContext(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, new(atomic.Uint64))
+}
+
+// ConnRequestSeqExtractor is a [ContextExtractor] that logs the number of
+// the request on its connection as net.conn_request_seq: 1 for the first
+// request, and 2 or more for requests that reused a keep-alive connection.
+// It requires [ConnContext], and logs nothing without it.
+func ConnRequestSeqExtractor(ctx context.Context) []slog.Attr {
+	if state := stateFromContext(ctx); state != nil && state.connSeq > 0 {
+		return []slog.Attr{slog.Uint64("net.conn_request_seq", state.connSeq)}
+	}
+	return nil
+}
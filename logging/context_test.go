@@ -0,0 +1,196 @@
+package logging_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+func TestRouteFromContext(t *testing.T) {
+	var route string
+	var found bool
+	var id string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /foo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		route, found = logging.RouteFromContext(r.Context())
+		id = r.PathValue("id")
+	})
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/foo/1234", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(slog.New(slog.DiscardHandler)))
+	mw.ServeHTTP(rr, r)
+
+	assert.True(t, found)
+	assert.Equal(t, "GET /foo/{id}", route)
+	assert.Equal(t, "1234", id)
+}
+
+func TestRouteFromContext_NoRoute(t *testing.T) {
+	found := true
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, found = logging.RouteFromContext(r.Context())
+	})
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(h, logging.WithLogger(slog.New(slog.DiscardHandler)))
+	mw.ServeHTTP(rr, r)
+
+	assert.False(t, found)
+}
+
+func TestRouteFromContext_Nested(t *testing.T) {
+	var route string
+	var found bool
+
+	th := &testHandler{}
+	inner := logging.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, found = logging.RouteFromContext(r.Context())
+	}), logging.WithLogger(slog.New(th)))
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /foo/{id}", inner)
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/foo/1234", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(slog.New(slog.DiscardHandler)))
+	mw.ServeHTTP(rr, r)
+
+	assert.True(t, found)
+	assert.Equal(t, "GET /foo/{id}", route)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, "GET /foo/{id}", attrs["http.route"].Value.String())
+}
+
+func TestNamed(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+
+	mux := http.NewServeMux()
+	mux.Handle("/", logging.Named("catchall", http.NotFoundHandler()))
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger))
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, "catchall", attrs["handler.name"].Value.String())
+}
+
+func TestConnRequestSeqExtractor(t *testing.T) {
+	th := &syncHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	srv := httptest.NewUnstartedServer(logging.Wrap(mux,
+		logging.WithLogger(logger),
+		logging.WithContextExtractors(logging.ConnRequestSeqExtractor),
+	))
+	srv.Config.ConnContext = logging.ConnContext
+	srv.Start()
+	defer srv.Close()
+
+	client := srv.Client()
+	for range 2 {
+		resp, err := client.Get(srv.URL)
+		assert.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	client.CloseIdleConnections()
+	resp, err := client.Get(srv.URL)
+	assert.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	var seqs []uint64
+	for _, rec := range th.snapshot() {
+		rec.Attrs(func(a slog.Attr) bool {
+			if a.Key == "net.conn_request_seq" {
+				seqs = append(seqs, a.Value.Uint64())
+			}
+			return true
+		})
+	}
+	assert.Equal(t, []uint64{1, 2, 1}, seqs)
+}
+
+func TestConnRequestSeqExtractor_NoConnContext(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	mw := logging.Wrap(mux,
+		logging.WithLogger(logger),
+		logging.WithContextExtractors(logging.ConnRequestSeqExtractor),
+	)
+	mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Len(t, th.records, 1)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		assert.NotEqual(t, "net.conn_request_seq", a.Key)
+		return true
+	})
+}
+
+func TestConnRequestSeqExtractor_Nested(t *testing.T) {
+	th := &syncHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+	opts := []logging.Option{
+		logging.WithLogger(logger),
+		logging.WithContextExtractors(logging.ConnRequestSeqExtractor),
+	}
+
+	srv := httptest.NewUnstartedServer(logging.Wrap(logging.Wrap(mux, opts...), opts...))
+	srv.Config.ConnContext = logging.ConnContext
+	srv.Start()
+	defer srv.Close()
+
+	client := srv.Client()
+	for range 2 {
+		resp, err := client.Get(srv.URL)
+		assert.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+
+	var seqs []uint64
+	for _, rec := range th.snapshot() {
+		rec.Attrs(func(a slog.Attr) bool {
+			if a.Key == "net.conn_request_seq" {
+				seqs = append(seqs, a.Value.Uint64())
+			}
+			return true
+		})
+	}
+	assert.Equal(t, []uint64{1, 1, 2, 2}, seqs)
+}
@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// dedupKey identifies identical error records. Requests with a route are
+// identified by it rather than by their path.
+type dedupKey struct {
+	route  string
+	path   string
+	status int
+}
+
+// dedupCounts tracks the error records suppressed by [WithDedupWindow].
+type dedupCounts struct {
+	mu         sync.Mutex
+	suppressed map[dedupKey]int
+}
+
+// dedup reports whether an error record for the route, or path if there is no
+// route, and status repeats one logged within the window, and should be
+// suppressed. The first record of a window is kept, and when the window
+// closes a summary of the suppressed records, if any, is logged.
+func (m *Middleware) dedup(route, path string, status int) bool {
+	key := dedupKey{route: route, status: status}
+	target := slog.String(m.keys.route, route)
+	if route == "" {
+		key.path = path
+		target = slog.String(m.keys.path, path)
+	}
+
+	m.dedupCounts.mu.Lock()
+	defer m.dedupCounts.mu.Unlock()
+
+	if n, ok := m.dedupCounts.suppressed[key]; ok {
+		m.dedupCounts.suppressed[key] = n + 1
+		return true
+	}
+
+	if m.dedupCounts.suppressed == nil {
+		m.dedupCounts.suppressed = make(map[dedupKey]int)
+	}
+	m.dedupCounts.suppressed[key] = 0
+	time.AfterFunc(m.dedupWindow, func() {
+		m.dedupCounts.mu.Lock()
+		n := m.dedupCounts.suppressed[key]
+		delete(m.dedupCounts.suppressed, key)
+		m.dedupCounts.mu.Unlock()
+
+		if n > 0 {
+			m.logger.LogAttrs(context.Background(), slog.LevelError,
+				fmt.Sprintf("%s [%d] repeated %d times", target.Value, status, n),
+				slog.Int(m.keys.status, status),
+				target,
+				slog.Int("repeated", n),
+			)
+		}
+	})
+	return false
+}
+
+// WithDedupWindow collapses identical error records, those logged at Error
+// or above with the same route, or path if there is no route, and status,
+// that occur within d of the first one. The first record is logged as usual,
+// and the rest are counted and summarized in a single record with a
+// repeated attribute when the window closes. This cuts the noise of tight
+// error loops during incidents.
+func WithDedupWindow(d time.Duration) Option {
+	return func(mw *Middleware) {
+		mw.dedupWindow = d
+	}
+}
@@ -0,0 +1,75 @@
+package logging_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+// syncHandler is a [testHandler] that can be logged to from other
+// goroutines.
+type syncHandler struct {
+	mu sync.Mutex
+	testHandler
+}
+
+func (s *syncHandler) Handle(ctx context.Context, rec slog.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.testHandler.Handle(ctx, rec)
+}
+
+func (s *syncHandler) snapshot() []slog.Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]slog.Record(nil), s.records...)
+}
+
+func TestMiddleware_WithDedupWindow(t *testing.T) {
+	sh := &syncHandler{}
+	logger := slog.New(sh)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /fail", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithDedupWindow(50*time.Millisecond))
+
+	for _, path := range []string{"/fail", "/ok", "/fail", "/fail", "/ok", "/fail"} {
+		mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	records := sh.snapshot()
+	assert.Len(t, records, 3, "first error and both successes")
+
+	assert.Eventually(t, func() bool {
+		return len(sh.snapshot()) == 4
+	}, time.Second, 10*time.Millisecond)
+
+	summary := sh.snapshot()[3]
+	assert.Equal(t, slog.LevelError, summary.Level)
+	assert.Equal(t, "GET /fail [500] repeated 3 times", summary.Message)
+	attrs := make(map[string]slog.Attr)
+	summary.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, int64(3), attrs["repeated"].Value.Int64())
+	assert.Equal(t, "GET /fail", attrs["http.route"].Value.String())
+	assert.Equal(t, int64(http.StatusInternalServerError), attrs["http.status_code"].Value.Int64())
+
+	// A new window starts after the previous one closes.
+	mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	assert.Len(t, sh.snapshot(), 5)
+}
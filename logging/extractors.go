@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// maxConcurrentExtractors bounds how many extractors run at once with
+// [WithConcurrentExtractors].
+const maxConcurrentExtractors = 8
+
+// extractAll calls every extractor and returns their attributes in the order
+// the extractors were given.
+func (m *Middleware) extractAll(ctx context.Context) []slog.Attr {
+	if !m.parallelExtract || len(m.extractors) < 2 {
+		var extracted []slog.Attr
+		for i, fn := range m.extractors {
+			extracted = append(extracted, m.extract(ctx, i, fn)...)
+		}
+		return extracted
+	}
+
+	results := make([][]slog.Attr, len(m.extractors))
+	panics := make([]any, len(m.extractors))
+	sem := make(chan struct{}, maxConcurrentExtractors)
+	var wg sync.WaitGroup
+	for i, fn := range m.extractors {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				panics[i] = recover()
+				<-sem
+				wg.Done()
+			}()
+			results[i] = m.extract(ctx, i, fn)
+		}()
+	}
+	wg.Wait()
+
+	var extracted []slog.Attr
+	for i, attrs := range results {
+		if panics[i] != nil {
+			panic(panics[i])
+		}
+		extracted = append(extracted, attrs...)
+	}
+	return extracted
+}
+
+// extract calls the extractor fn. With [WithExtractorTimeout], fn contributes
+// no attributes if it does not return in time.
+func (m *Middleware) extract(ctx context.Context, i int, fn ContextExtractor) []slog.Attr {
+	if m.extractTimeout <= 0 {
+		return m.callExtractor(ctx, i, fn)
+	}
+
+	type result struct {
+		attrs    []slog.Attr
+		panicked any
+	}
+
+	// Only the extractor's own run time counts: the request context may
+	// already be canceled or past its deadline by the time it is logged.
+	extractCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.extractTimeout)
+	defer cancel()
+
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- result{panicked: v}
+			}
+		}()
+		done <- result{attrs: m.callExtractor(extractCtx, i, fn)}
+	}()
+
+	select {
+	case res := <-done:
+		if res.panicked != nil {
+			panic(res.panicked)
+		}
+		return res.attrs
+	case <-extractCtx.Done():
+		m.logger.LogAttrs(ctx, slog.LevelWarn, "context extractor timed out",
+			slog.Int("extractor", i),
+			slog.Duration("timeout", m.extractTimeout),
+		)
+		return nil
+	}
+}
+
+// callExtractor calls the extractor fn. With [WithSafeExtractors], a panic in
+// fn is logged as a warning and fn contributes no attributes.
+func (m *Middleware) callExtractor(ctx context.Context, i int, fn ContextExtractor) (attrs []slog.Attr) {
+	if m.safeExtractors {
+		defer func() {
+			if v := recover(); v != nil {
+				m.logger.LogAttrs(ctx, slog.LevelWarn, "context extractor panicked",
+					slog.Int("extractor", i),
+					slog.Any("panic", v),
+				)
+				attrs = nil
+			}
+		}()
+	}
+	return fn(ctx)
+}
+
+// WithExtractorTimeout limits how long each [ContextExtractor] may run. Each
+// extractor is passed a context with a deadline d from now, even if the
+// request context is already done, and if it has not returned by then a
+// warning is logged and the access log is recorded without its attributes.
+// The extractor keeps running in the background, so it should respect the
+// context's deadline.
+func WithExtractorTimeout(d time.Duration) Option {
+	return func(mw *Middleware) {
+		mw.extractTimeout = d
+	}
+}
+
+// WithConcurrentExtractors runs the [ContextExtractor] functions in parallel,
+// up to 8 at a time, rather than one after the other, for extractors that do
+// independent, latency-bound work such as cache lookups. Their attributes are
+// still logged in the order the extractors were given, and
+// [WithExtractorTimeout] applies to each of them.
+func WithConcurrentExtractors() Option {
+	return func(mw *Middleware) {
+		mw.parallelExtract = true
+	}
+}
+
+// SubjectExtractor returns a [ContextExtractor] that logs the authenticated
+// user or subject that auth middleware stored in the context under key. The
+// value must be a string or a [fmt.Stringer], and is logged as attrKey, or
+// enduser.id if attrKey is empty. Requests without a subject get no
+// attribute.
+func SubjectExtractor(key any, attrKey string) ContextExtractor {
+	if attrKey == "" {
+		attrKey = "enduser.id"
+	}
+	return func(ctx context.Context) []slog.Attr {
+		var subject string
+		switch v := ctx.Value(key).(type) {
+		case string:
+			subject = v
+		case fmt.Stringer:
+			subject = v.String()
+		}
+		if subject == "" {
+			return nil
+		}
+		return []slog.Attr{slog.String(attrKey, subject)}
+	}
+}
@@ -0,0 +1,215 @@
+package logging_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+func TestMiddleware_WithExtractorTimeout(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	timeout := 20 * time.Millisecond
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithExtractorTimeout(timeout), logging.WithContextExtractors(
+		func(ctx context.Context) []slog.Attr {
+			<-time.After(time.Second)
+			return []slog.Attr{slog.String("slow", "done")}
+		},
+		func(ctx context.Context) []slog.Attr {
+			_, hasDeadline := ctx.Deadline()
+			return []slog.Attr{slog.Bool("fast", hasDeadline)}
+		},
+	))
+
+	start := time.Now()
+	mw.ServeHTTP(rr, r)
+	assert.Less(t, time.Since(start), 10*timeout)
+
+	assert.Len(t, th.records, 2)
+	assert.Equal(t, slog.LevelWarn, th.records[0].Level)
+	assert.Equal(t, "context extractor timed out", th.records[0].Message)
+
+	attrs := make(map[string]slog.Attr)
+	th.records[1].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.NotContains(t, attrs, "slow")
+	assert.True(t, attrs["fast"].Value.Bool())
+}
+
+func TestMiddleware_WithExtractorTimeout_Panics(t *testing.T) {
+	mw := logging.Wrap(http.NewServeMux(),
+		logging.WithLogger(slog.New(slog.DiscardHandler)),
+		logging.WithExtractorTimeout(time.Second),
+		logging.WithContextExtractors(func(context.Context) []slog.Attr {
+			panic("boom")
+		}),
+	)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestMiddleware_WithExtractorTimeout_DoneRequest(t *testing.T) {
+	f := func(opts ...logging.Option) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+			defer cancel()
+			r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+			mw := logging.Wrap(mux, append(opts,
+				logging.WithLogger(logger),
+				logging.WithExtractorTimeout(time.Second),
+				logging.WithContextExtractors(
+					func(context.Context) []slog.Attr { return []slog.Attr{slog.String("trace_id", "abc")} },
+					func(context.Context) []slog.Attr { return []slog.Attr{slog.String("enduser.id", "user-1")} },
+				),
+			)...)
+
+			mw.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Len(t, th.records, 1)
+			assert.NotEqual(t, "context extractor timed out", th.records[0].Message)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, "abc", attrs["trace_id"].Value.String())
+			assert.Equal(t, "user-1", attrs["enduser.id"].Value.String())
+		}
+	}
+
+	t.Run("sequential", f())
+	t.Run("concurrent", f(logging.WithConcurrentExtractors()))
+}
+
+type subjectKey struct{}
+
+type userID int
+
+func (id userID) String() string {
+	return "user-" + strconv.Itoa(int(id))
+}
+
+func TestSubjectExtractor(t *testing.T) {
+	f := func(subject any, attrKey string, expected []slog.Attr) func(*testing.T) {
+		return func(t *testing.T) {
+			ctx := context.Background()
+			if subject != nil {
+				ctx = context.WithValue(ctx, subjectKey{}, subject)
+			}
+
+			attrs := logging.SubjectExtractor(subjectKey{}, attrKey)(ctx)
+
+			assert.Equal(t, expected, attrs)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		subject  any
+		attrKey  string
+		expected []slog.Attr
+	}{
+		{
+			name:     "string subject",
+			subject:  "alice",
+			expected: []slog.Attr{slog.String("enduser.id", "alice")},
+		},
+		{
+			name:     "stringer subject",
+			subject:  userID(42),
+			expected: []slog.Attr{slog.String("enduser.id", "user-42")},
+		},
+		{
+			name:     "custom key",
+			subject:  "alice",
+			attrKey:  "user",
+			expected: []slog.Attr{slog.String("user", "alice")},
+		},
+		{
+			name: "no subject",
+		},
+		{
+			name:    "unsupported type",
+			subject: 42,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.subject, tc.attrKey, tc.expected))
+	}
+}
+
+func TestMiddleware_WithConcurrentExtractors(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	sleepy := func(key string, d time.Duration) logging.ContextExtractor {
+		return func(context.Context) []slog.Attr {
+			time.Sleep(d)
+			return []slog.Attr{slog.Bool(key, true)}
+		}
+	}
+
+	mw := logging.Wrap(mux,
+		logging.WithLogger(logger),
+		logging.WithConcurrentExtractors(),
+		logging.WithContextExtractors(
+			sleepy("first", 60*time.Millisecond),
+			sleepy("second", 40*time.Millisecond),
+			sleepy("third", 20*time.Millisecond),
+		),
+	)
+
+	start := time.Now()
+	mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Less(t, time.Since(start), 110*time.Millisecond)
+	assert.Len(t, th.records, 1)
+	var keys []string
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		keys = append(keys, a.Key)
+		return true
+	})
+	assert.Equal(t, []string{"first", "second", "third"}, keys[len(keys)-3:])
+}
+
+func TestMiddleware_WithConcurrentExtractors_Panics(t *testing.T) {
+	mw := logging.Wrap(http.NewServeMux(),
+		logging.WithLogger(slog.New(slog.DiscardHandler)),
+		logging.WithConcurrentExtractors(),
+		logging.WithContextExtractors(
+			noopExtractor,
+			func(context.Context) []slog.Attr {
+				panic("boom")
+			},
+		),
+	)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -0,0 +1,132 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// canServeFast reports whether m is configured with nothing beyond the logger,
+// [Leveler], key names and message, so that [Middleware.serveFast] produces
+// the same log as the general path.
+func (m *Middleware) canServeFast() bool {
+	return len(m.filteredPaths) == 0 &&
+		len(m.filteredRoutes) == 0 &&
+		len(m.allowedPaths) == 0 &&
+		len(m.extractors) == 0 &&
+		!m.splitRoute &&
+		!m.serverAddr &&
+		m.warmupLimit <= 0 &&
+		!m.checkLength &&
+		m.retryHeader == "" &&
+		m.maxPathLength <= 0 &&
+		!m.logAccept &&
+		len(m.formFields) == 0 &&
+		m.sampleRate >= 1 &&
+		!m.initialBudget &&
+		!m.defaultNotFound &&
+		!m.wrapsBody() &&
+		m.pathTemplate == nil &&
+		!m.panicOnly &&
+		!m.statusClass &&
+		!m.outcome &&
+		len(m.deployment) == 0 &&
+		m.instanceName == "" &&
+		m.controlHeader == "" &&
+		m.queueHeader == "" &&
+		!m.entryDelay &&
+		len(m.versionInfo) == 0 &&
+		m.unsupported == UnsupportedError &&
+		!m.sequence &&
+		m.overrideHeader == "" &&
+		m.mux == nil &&
+		m.maxHeaderLength <= 0 &&
+		!m.sanitizePath &&
+		!m.contentEncoding &&
+		!m.allowedMethods &&
+		m.cacheHeader == "" &&
+		m.captureRate <= 0 &&
+		!m.routeDepth &&
+		m.dedupWindow <= 0 &&
+		!m.tlsInfo &&
+		!m.bandwidth &&
+		m.messageFunc == nil &&
+		!m.fullURL &&
+		m.canceledLevel == nil &&
+		len(m.attrOrder) == 0 &&
+		!m.clientCert &&
+		m.timingGroup == "" &&
+		!m.goroutines &&
+		!m.levelDiag
+}
+
+// serveFast is [Middleware.ServeHTTP] for the common case where no optional
+// features are enabled. It avoids the filter lookups and builds the
+// attributes in a fixed-size array rather than a growing slice.
+func (m *Middleware) serveFast(w http.ResponseWriter, r *http.Request) {
+	ww := &wrappedWriter{
+		ResponseWriter: w,
+	}
+	start := time.Now()
+
+	state, ctx := newRequestState(r.Context())
+	r = r.WithContext(ctx)
+
+	defer func() {
+		var buf [12]slog.Attr
+		attrs := append(buf[:0],
+			slog.Int(m.keys.status, ww.status),
+			slog.String(m.keys.path, r.URL.Path),
+			slog.String(m.keys.method, r.Method),
+			slog.Any(m.keys.duration, time.Since(start)),
+		)
+
+		if !m.noRequestSize && r.ContentLength >= 0 {
+			attrs = append(attrs, slog.Int64(m.keys.requestSize, r.ContentLength))
+		}
+
+		if !m.noResponseSize {
+			attrs = append(attrs, slog.Int64(m.keys.responseSize, ww.bytes))
+		}
+
+		if state.route != "" {
+			attrs = append(attrs, slog.String(m.keys.route, state.route))
+		}
+
+		level := m.leveler(ww.status)
+		if ww.writeErr != nil {
+			attrs = append(attrs, slog.String("http.write_error", ww.writeErr.Error()))
+			level = max(level, slog.LevelWarn)
+		}
+
+		if ww.pushes > 0 {
+			attrs = append(attrs, slog.Bool("http.pushed", true))
+		}
+
+		if ww.locked {
+			attrs = append(attrs, slog.Bool("http.status_locked", true))
+		}
+
+		if ww.hijacked {
+			attrs = append(attrs, slog.Bool("http.hijacked", true))
+		}
+
+		if state.handlerName != "" {
+			attrs = append(attrs, slog.String("handler.name", state.handlerName))
+		}
+
+		var msg string
+		if !m.noMessage {
+			msg = fmt.Sprintf("%s %s [%d]", r.Method, r.URL.Path, ww.status)
+		}
+
+		m.logger.LogAttrs(r.Context(), level, msg, attrs...)
+	}()
+
+	if h, ok := m.target.(*http.ServeMux); ok {
+		_, state.route = h.Handler(r)
+	}
+
+	m.target.ServeHTTP(ww.writer(), r)
+}
@@ -0,0 +1,97 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+// noopExtractor forces the general path without changing the output.
+func noopExtractor(context.Context) []slog.Attr {
+	return nil
+}
+
+func newTextLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+}
+
+func TestMiddleware_FastPathMatchesGeneralPath(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /foo/{id}", http.NotFound)
+	mux.Handle("GET /named", logging.Named("named", http.NotFoundHandler()))
+	mux.HandleFunc("POST /error", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/foo/1234", nil),
+		httptest.NewRequest(http.MethodGet, "/named", nil),
+		httptest.NewRequest(http.MethodPost, "/error", nil),
+		httptest.NewRequest(http.MethodGet, "/missing", nil),
+	}
+
+	f := func(opts ...logging.Option) func(*testing.T) {
+		return func(t *testing.T) {
+			var fastBuf, generalBuf bytes.Buffer
+			fast := logging.Wrap(mux, append(opts, logging.WithLogger(newTextLogger(&fastBuf)))...)
+			general := logging.Wrap(mux, append(opts,
+				logging.WithLogger(newTextLogger(&generalBuf)),
+				logging.WithContextExtractors(noopExtractor),
+			)...)
+
+			for _, r := range requests {
+				fast.ServeHTTP(httptest.NewRecorder(), r)
+				general.ServeHTTP(httptest.NewRecorder(), r)
+			}
+
+			assert.NotEmpty(t, fastBuf.String())
+			assert.Equal(t, generalBuf.String(), fastBuf.String())
+		}
+	}
+
+	t.Run("defaults", f())
+	t.Run("semconv keys", f(logging.WithOTelSemconv()))
+	t.Run("without message", f(logging.WithoutMessage()))
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /foo/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	logger := slog.New(slog.DiscardHandler)
+
+	f := func(h http.Handler) func(*testing.B) {
+		return func(b *testing.B) {
+			r := httptest.NewRequest(http.MethodGet, "/foo/1234", nil)
+			w := httptest.NewRecorder()
+			b.ReportAllocs()
+			for b.Loop() {
+				h.ServeHTTP(w, r)
+			}
+		}
+	}
+
+	b.Run("fast path", f(logging.Wrap(mux, logging.WithLogger(logger))))
+	b.Run("general path", f(logging.Wrap(mux, logging.WithLogger(logger), logging.WithContextExtractors(noopExtractor))))
+
+	// Unlike the discard handler above, the raw output does render the line.
+	text := slog.New(slog.NewTextHandler(io.Discard, nil))
+	b.Run("fast path text", f(logging.Wrap(mux, logging.WithLogger(text))))
+	b.Run("raw output", f(logging.Wrap(mux, logging.WithRawOutput(io.Discard, nil))))
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// fanoutHandler passes each record to several handlers.
+type fanoutHandler []slog.Handler
+
+func (f fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanoutHandler) Handle(ctx context.Context, rec slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, rec.Level) {
+			if err := h.Handle(ctx, rec.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (f fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
+
+// WithLoggers sends every record to each of loggers, e.g. to write access
+// logs to stdout and to a separate audit sink, in place of the logger set by
+// [WithLogger]. The record is built once, and each logger applies its own
+// level and attributes to it. Nil loggers are skipped, and if there are no
+// others the logger is left unchanged.
+func WithLoggers(loggers ...*slog.Logger) Option {
+	return func(mw *Middleware) {
+		var handlers fanoutHandler
+		for _, l := range loggers {
+			if l != nil {
+				handlers = append(handlers, l.Handler())
+			}
+		}
+		switch len(handlers) {
+		case 0:
+			// Keep the current logger rather than leave none.
+		case 1:
+			mw.logger = slog.New(handlers[0])
+		default:
+			mw.logger = slog.New(handlers)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package logging_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+func TestMiddleware_WithLoggers(t *testing.T) {
+	th := &testHandler{}
+	var audit, errorsOnly bytes.Buffer
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	mw := logging.Wrap(mux, logging.WithLoggers(
+		slog.New(th),
+		newTextLogger(&audit).With("sink", "audit"),
+		slog.New(slog.NewTextHandler(&errorsOnly, &slog.HandlerOptions{Level: slog.LevelError})),
+	))
+
+	mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Len(t, th.records, 1)
+	assert.Equal(t, slog.LevelInfo, th.records[0].Level)
+	assert.Equal(t, "GET / [404]", th.records[0].Message)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, int64(http.StatusNotFound), attrs["http.status_code"].Value.Int64())
+
+	assert.Equal(t, "level=INFO msg=\"GET / [404]\" sink=audit http.status_code=404 http.path=/ http.method=GET http.request_size=0 http.response_size=0 http.route=/\n", audit.String())
+	assert.Empty(t, errorsOnly.String())
+}
+
+func TestMiddleware_WithLoggers_Empty(t *testing.T) {
+	f := func(loggers ...*slog.Logger) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			mux := http.NewServeMux()
+
+			mw := logging.Wrap(mux, logging.WithLogger(slog.New(th)), logging.WithLoggers(loggers...))
+
+			assert.NotPanics(t, func() {
+				mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			})
+			assert.Len(t, th.records, 1)
+		}
+	}
+
+	t.Run("no loggers", f())
+	t.Run("nil logger", f(nil))
+}
@@ -6,31 +6,28 @@ package logging
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
 	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 )
 
-var _ http.ResponseWriter = &wrappedWriter{}
-
-type wrappedWriter struct {
-	http.ResponseWriter
-	status int
-}
-
-func (w *wrappedWriter) WriteHeader(code int) {
-	w.status = code
-	w.ResponseWriter.WriteHeader(code)
-}
-
-func (w *wrappedWriter) Write(data []byte) (int, error) {
-	if w.status == 0 {
-		w.status = http.StatusOK
-	}
-	return w.ResponseWriter.Write(data)
-}
-
 // ContextExtractor functions are used to pull additional attributes out of a
 // [context.Context] instance. See
 // [jsocol.io/middleware/logging/pkg/otelextractor.New] for an example.
@@ -48,18 +45,138 @@ var defaultLeveler Leveler = func(status int) slog.Level {
 	return slog.LevelInfo
 }
 
+// BaseLevelMode is how [WithBaseLevel] combines its level with the level
+// from the [Leveler].
+type BaseLevelMode int
+
+const (
+	// BaseLevelOverride logs every request at the base level.
+	BaseLevelOverride BaseLevelMode = iota
+	// BaseLevelFloor logs every request at least at the base level.
+	BaseLevelFloor
+)
+
+// attrKeys holds the keys used for the built-in attributes.
+type attrKeys struct {
+	status       string
+	path         string
+	method       string
+	route        string
+	duration     string
+	requestSize  string
+	responseSize string
+}
+
+var defaultKeys = attrKeys{
+	status:       "http.status_code",
+	path:         "http.path",
+	method:       "http.method",
+	route:        "http.route",
+	duration:     "duration",
+	requestSize:  "http.request_size",
+	responseSize: "http.response_size",
+}
+
+// otelKeys follow the OpenTelemetry HTTP semantic conventions where one
+// exists.
+var otelKeys = attrKeys{
+	status:       "http.response.status_code",
+	path:         "url.path",
+	method:       "http.request.method",
+	route:        "http.route",
+	duration:     "duration",
+	requestSize:  "http.request.body.size",
+	responseSize: "http.response.body.size",
+}
+
 var _ http.Handler = &Middleware{}
 
 // Middleware is an [http.Handler] that records access logs for every request
 // handled by the wrapped [http.Handler]. See the [Option] functions for more
 // configuration options.
 type Middleware struct {
-	target         http.Handler
-	logger         *slog.Logger
-	leveler        Leveler
-	filteredPaths  map[string]struct{}
-	filteredRoutes map[string]struct{}
-	extractors     []ContextExtractor
+	target          http.Handler
+	logger          *slog.Logger
+	keys            attrKeys
+	leveler         Leveler
+	filteredPaths   map[string]struct{}
+	filteredRoutes  map[string]struct{}
+	allowedPaths    []string
+	extractors      []ContextExtractor
+	splitRoute      bool
+	serverAddr      bool
+	serverAttrs     []slog.Attr
+	warmupLimit     int
+	warmupMu        sync.Mutex
+	warmupCounts    map[string]int
+	checkLength     bool
+	retryHeader     string
+	maxPathLength   int
+	noMessage       bool
+	noRequestSize   bool
+	chunkedSize     bool
+	noResponseSize  bool
+	logAccept       bool
+	formFields      []string
+	safeExtractors  bool
+	sampleRate      float64
+	sampleSlow      time.Duration
+	sampleKey       func(*http.Request) (uint64, bool)
+	initialBudget   bool
+	extractorGroup  string
+	defaultNotFound bool
+	bodyFirstRead   bool
+	largeBody       int64
+	bodyHash        func() hash.Hash
+	bodyHashSize    int
+	pathTemplate    func(string) string
+	panicOnly       bool
+	statusClass     bool
+	outcome         bool
+	deployment      []slog.Attr
+	instanceName    string
+	controlHeader   string
+	queueHeader     string
+	entryDelay      bool
+	versionInfo     []slog.Attr
+	rawOut          io.Writer
+	rawFormat       Formatter
+	rawMu           sync.Mutex
+	unsupported     UnsupportedPolicy
+	sequence        bool
+	seq             atomic.Uint64
+	overrideHeader  string
+	mux             *http.ServeMux
+	maxHeaderLength int
+	sanitizePath    bool
+	contentEncoding bool
+	parallelExtract bool
+	allowedMethods  bool
+	baseLevel       *slog.Level
+	baseLevelMode   BaseLevelMode
+	statusLeveler   Leveler
+	levelDiag       bool
+	sniffType       bool
+	cacheHeader     string
+	captureRate     float64
+	captureMax      int
+	routeDepth      bool
+	pathDepth       bool
+	dedupWindow     time.Duration
+	dedupCounts     dedupCounts
+	tlsInfo         bool
+	bandwidth       bool
+	messageFunc     func([]slog.Attr) string
+	fullURL         bool
+	noURLQuery      bool
+	trustForwarded  bool
+	canceledLevel   *slog.Level
+	attrOrder       []string
+	extractTimeout  time.Duration
+	clientCert      bool
+	timingGroup     string
+	goroutines      bool
+	fast            bool
 }
 
 // Wrap returns a new [http.Handler] that is wrapped in a loggin [Middleware]
@@ -67,9 +184,30 @@ type Middleware struct {
 // [*http.ServeMux], it will attempt to extract the matching route as well as
 // the request path.
 func Wrap(h http.Handler, opts ...Option) http.Handler {
+	return newMiddleware(h, opts...)
+}
+
+// New is like [Wrap] but validates the options and returns an error
+// describing any invalid or conflicting configuration, so that mistakes can
+// fail fast at startup.
+func New(h http.Handler, opts ...Option) (http.Handler, error) {
+	if h == nil {
+		return nil, errors.New("logging: handler is nil")
+	}
+
+	m := newMiddleware(h, opts...)
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func newMiddleware(h http.Handler, opts ...Option) *Middleware {
 	m := &Middleware{
 		target:         h,
 		logger:         slog.Default(),
+		keys:           defaultKeys,
+		sampleRate:     1,
 		filteredPaths:  make(map[string]struct{}),
 		filteredRoutes: make(map[string]struct{}),
 	}
@@ -81,65 +219,703 @@ func Wrap(h http.Handler, opts ...Option) http.Handler {
 	if m.leveler == nil {
 		m.leveler = defaultLeveler
 	}
+	m.statusLeveler = m.leveler
+	if m.baseLevel != nil {
+		base, leveler := *m.baseLevel, m.leveler
+		if m.baseLevelMode == BaseLevelOverride {
+			m.leveler = func(int) slog.Level { return base }
+		} else {
+			m.leveler = func(status int) slog.Level { return max(leveler(status), base) }
+		}
+	}
+
+	m.fast = m.canServeFast()
 
 	return m
 }
 
+// validate reports invalid or conflicting options.
+func (m *Middleware) validate() error {
+	var errs []error
+
+	if m.logger == nil {
+		errs = append(errs, errors.New("logging: logger is nil"))
+	}
+	if len(m.allowedPaths) > 0 && len(m.filteredPaths) > 0 {
+		errs = append(errs, errors.New("logging: path allowlist and path filter are mutually exclusive"))
+	}
+	if m.sampleRate < 0 || m.sampleRate > 1 {
+		errs = append(errs, fmt.Errorf("logging: sample rate %v is not between 0 and 1", m.sampleRate))
+	}
+	if m.warmupCounts != nil && m.warmupLimit <= 0 {
+		errs = append(errs, fmt.Errorf("logging: warmup limit %d is not positive", m.warmupLimit))
+	}
+	if m.captureRate < 0 || m.captureRate > 1 {
+		errs = append(errs, fmt.Errorf("logging: body capture rate %v is not between 0 and 1", m.captureRate))
+	}
+	if m.captureRate > 0 && m.captureMax <= 0 {
+		errs = append(errs, fmt.Errorf("logging: body capture size %d is not positive", m.captureMax))
+	}
+	if m.rawFormat != nil && m.rawOut == nil {
+		errs = append(errs, errors.New("logging: raw output writer is nil"))
+	}
+
+	return errors.Join(errs...)
+}
+
 func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if m.rawFormat != nil {
+		m.serveRaw(w, r)
+		return
+	}
+	if m.fast {
+		m.serveFast(w, r)
+		return
+	}
+
 	ww := &wrappedWriter{
 		ResponseWriter: w,
+		controlHeader:  m.controlHeader,
 	}
 	start := time.Now()
-	var route string
+	var routed bool
+
+	state, ctx := newRequestState(r.Context())
+	r = r.WithContext(ctx)
+	route := state.route
+
+	switch m.unsupported {
+	case UnsupportedIgnore:
+		ww.unsupported = func(string) error { return nil }
+	case UnsupportedWarn:
+		ctx := r.Context()
+		ww.unsupported = func(iface string) error {
+			m.logger.LogAttrs(ctx, slog.LevelWarn, "response writer does not support "+iface,
+				slog.String(m.keys.path, r.URL.Path),
+				slog.String(m.keys.method, r.Method),
+			)
+			return nil
+		}
+	}
+
+	captureBodies := m.captureRate > 0 && m.captureMax > 0 && rand.Float64() < m.captureRate
+	if captureBodies {
+		ww.capture = &capture{max: m.captureMax}
+	}
+
+	var rb *body
+	if (m.wrapsBody() || captureBodies) && r.Body != nil {
+		rb = &body{ReadCloser: r.Body}
+		if captureBodies {
+			rb.capture = &capture{max: m.captureMax}
+		}
+		if m.bodyHash != nil {
+			rb.hash = m.bodyHash()
+		}
+		rb.sniffing = m.sniffType && r.Header.Get("Content-Type") == ""
+		r.Body = rb
+	}
+
+	entry, hasEntry := entryFromContext(r.Context())
+	if m.entryDelay && !hasEntry {
+		r = r.WithContext(contextWithEntry(r.Context(), start))
+	}
+
+	var seq uint64
+	if m.sequence {
+		seq = m.seq.Add(1)
+	}
+
+	var budget time.Duration
+	var hasBudget bool
+	if m.initialBudget {
+		if dl, ok := r.Context().Deadline(); ok {
+			budget, hasBudget = dl.Sub(start), true
+		}
+	}
 
 	defer func() {
-		if m.filterPath(r.URL.Path) || (route != "" && m.filterRoute(route)) {
+		duration := time.Since(start)
+
+		var panicked any
+		if m.panicOnly {
+			if panicked = recover(); panicked == nil {
+				return
+			}
+			defer panic(panicked)
+		}
+
+		ww.checkControl()
+		forced := false
+		if ww.forceLog != nil {
+			if !*ww.forceLog {
+				return
+			}
+			forced = true
+		}
+
+		if !forced && (m.filterPath(r.URL.Path) || (route != "" && m.filterRoute(route))) {
+			return
+		}
+
+		if !forced && panicked == nil && !m.sample(r, ww.status, duration) {
+			return
+		}
+
+		if !forced && m.warmupLimit > 0 && !m.warmup(route) {
 			return
 		}
 
 		ctx := r.Context()
+		path := r.URL.Path
+		sanitized := false
+		if m.sanitizePath {
+			path, sanitized = sanitize(path)
+		}
+		truncated := false
+		if m.maxPathLength > 0 {
+			path, truncated = truncate(path, m.maxPathLength)
+		}
+
 		attrs := []slog.Attr{
-			slog.Int("http.status_code", ww.status),
-			slog.String("http.path", r.URL.Path),
-			slog.String("http.method", r.Method),
-			slog.Any("duration", time.Since(start)),
+			slog.Int(m.keys.status, ww.status),
+			slog.String(m.keys.path, path),
+			slog.String(m.keys.method, r.Method),
+		}
+
+		if m.statusClass {
+			attrs = append(attrs, slog.String("http.status_class", statusClass(ww.status)))
+		}
+
+		if m.outcome {
+			attrs = append(attrs, slog.String("http.outcome", outcome(ctx, ww.status)))
+		}
+
+		if m.overrideHeader != "" {
+			if method := strings.TrimSpace(r.Header.Get(m.overrideHeader)); method != "" {
+				attrs = append(attrs, slog.String("http.method_effective", m.headerValue(strings.ToUpper(method))))
+			}
+		}
+
+		if m.sequence {
+			attrs = append(attrs, slog.Uint64("http.seq", seq))
+		}
+
+		// Timing attributes are collected separately if they are grouped.
+		var timing []slog.Attr
+		addTiming := func(a slog.Attr) {
+			if m.timingGroup != "" {
+				timing = append(timing, a)
+			} else {
+				attrs = append(attrs, a)
+			}
+		}
+		addTiming(slog.Any(m.keys.duration, duration))
+
+		if !m.noRequestSize {
+			if r.ContentLength >= 0 {
+				attrs = append(attrs, slog.Int64(m.keys.requestSize, r.ContentLength))
+			} else if m.chunkedSize && rb != nil {
+				attrs = append(attrs, slog.Int64(m.keys.requestSize, rb.bytes))
+			}
+		}
+
+		if !m.noResponseSize {
+			attrs = append(attrs, slog.Int64(m.keys.responseSize, ww.bytes))
+		}
+
+		if m.queueHeader != "" {
+			if delay, ok := queueDelay(r.Header.Get(m.queueHeader), start); ok {
+				addTiming(slog.Duration("http.queue_delay", delay))
+			}
+		}
+
+		if m.entryDelay && hasEntry {
+			addTiming(slog.Duration("http.middleware_delay", max(start.Sub(entry), 0)))
+		}
+
+		if truncated {
+			attrs = append(attrs, slog.Bool("http.path_truncated", true))
+		}
+
+		if sanitized {
+			attrs = append(attrs,
+				slog.String("http.path_raw", r.URL.EscapedPath()),
+				slog.Bool("http.path_suspicious", true),
+			)
+		}
+
+		if m.pathTemplate != nil {
+			attrs = append(attrs, slog.String("http.path_template", m.pathTemplate(r.URL.Path)))
+		}
+
+		if m.bodyFirstRead && rb != nil && !rb.firstRead.IsZero() {
+			addTiming(slog.Duration("http.body_first_read", rb.firstRead.Sub(start)))
+		}
+
+		if m.largeBody > 0 && rb != nil && rb.bytes > m.largeBody {
+			attrs = append(attrs, slog.Bool("http.large_body", true))
+		}
+
+		if m.bodyHash != nil && rb != nil && rb.bytes > 0 {
+			attrs = append(attrs, slog.String("http.body_hash", rb.sum(m.bodyHashSize)))
+		}
+
+		if captureBodies {
+			if rb != nil {
+				attrs = append(attrs, slog.String("http.request_body", rb.capture.String()))
+			}
+			attrs = append(attrs, slog.String("http.response_body", ww.capture.String()))
+		}
+
+		if rb != nil && len(rb.sniff) > 0 {
+			attrs = append(attrs, slog.String("http.request.sniffed_content_type", http.DetectContentType(rb.sniff)))
+		}
+
+		if hasBudget {
+			attrs = append(attrs, slog.Duration("http.initial_budget", budget))
 		}
 
 		if route != "" {
-			attrs = append(attrs, slog.String("http.route", route))
+			if m.splitRoute {
+				method, path := splitPattern(route)
+				attrs = append(attrs, slog.String(m.keys.route, path))
+				if method != "" {
+					attrs = append(attrs, slog.String("http.route_method", method))
+				}
+			} else {
+				attrs = append(attrs, slog.String(m.keys.route, route))
+			}
+		}
+
+		if m.routeDepth {
+			if route != "" {
+				_, pattern := splitPattern(route)
+				if i := strings.IndexByte(pattern, '/'); i >= 0 {
+					pattern = pattern[i:]
+				}
+				attrs = append(attrs, slog.Int("http.route_depth", depth(pattern)))
+			} else if m.pathDepth {
+				attrs = append(attrs, slog.Int("http.route_depth", depth(r.URL.Path)))
+			}
+		}
+
+		if m.fullURL {
+			u, cut := m.requestURL(r)
+			attrs = append(attrs, slog.String("http.url", u))
+			if cut {
+				attrs = append(attrs, slog.Bool("http.url_truncated", true))
+			}
+		}
+
+		if m.defaultNotFound && routed && route == "" && ww.status == http.StatusNotFound {
+			attrs = append(attrs, slog.Bool("http.not_found_default", true))
+		}
+
+		if m.serverAddr {
+			if m.serverAttrs != nil {
+				attrs = append(attrs, m.serverAttrs...)
+			} else if addr, ok := ctx.Value(http.LocalAddrContextKey).(net.Addr); ok {
+				attrs = append(attrs, serverAddrAttrs(addr.String())...)
+			}
+		}
+
+		attrs = append(attrs, m.tlsAttrs(r)...)
+
+		if m.logAccept {
+			if accept := r.Header.Get("Accept"); accept != "" {
+				attrs = append(attrs, slog.String("http.request.accept", m.headerValue(accept)))
+			}
+		}
+
+		if len(m.formFields) > 0 {
+			attrs = append(attrs, m.formAttrs(r)...)
+		}
+
+		if m.bandwidth && duration > 0 {
+			attrs = append(attrs, slog.Float64("http.bandwidth_bytes_per_sec", float64(ww.bytes)/duration.Seconds()))
+		}
+
+		if m.contentEncoding {
+			if enc := ww.Header().Get("Content-Encoding"); enc != "" && enc != "identity" {
+				attrs = append(attrs, slog.String("http.response.content_encoding", m.headerValue(enc)))
+			}
+		}
+
+		if m.cacheHeader != "" {
+			if status := ww.Header().Get(m.cacheHeader); status != "" {
+				attrs = append(attrs, slog.String("http.cache_status", m.headerValue(status)))
+				if hit, ok := cacheHit(status); ok {
+					attrs = append(attrs, slog.Bool("http.cache_hit", hit))
+				}
+			}
+		}
+
+		if m.allowedMethods && ww.status == http.StatusMethodNotAllowed {
+			if allow := ww.Header().Get("Allow"); allow != "" {
+				attrs = append(attrs, slog.String("http.allowed_methods", m.headerValue(allow)))
+			}
+		}
+
+		if m.retryHeader != "" {
+			if n, err := strconv.Atoi(r.Header.Get(m.retryHeader)); err == nil && n >= 0 {
+				attrs = append(attrs,
+					slog.Int("http.retry_count", n),
+					slog.Bool("http.is_retry", n > 0),
+				)
+			}
+		}
+
+		level, reason := m.leveler(ww.status), ""
+		if m.levelDiag {
+			reason = m.levelReason(ww.status)
+		}
+		if m.checkLength && contentLengthMismatch(r, ww) {
+			attrs = append(attrs, slog.Bool("http.content_length_mismatch", true))
+			if level < slog.LevelWarn {
+				level, reason = slog.LevelWarn, "content_length_mismatch"
+			}
+		}
+		if ww.writeErr != nil {
+			attrs = append(attrs, slog.String("http.write_error", ww.writeErr.Error()))
+			if level < slog.LevelWarn {
+				level, reason = slog.LevelWarn, "write_error"
+			}
+		}
+		if m.canceledLevel != nil && errors.Is(ctx.Err(), context.Canceled) {
+			level, reason = *m.canceledLevel, "canceled"
+		}
+		if panicked != nil {
+			attrs = append(attrs, slog.String("http.panic", fmt.Sprint(panicked)))
+			level, reason = slog.LevelError, "panic"
+		}
+		if m.levelDiag {
+			attrs = append(attrs, slog.String("log.level_reason", reason))
+		}
+
+		if m.dedupWindow > 0 && level >= slog.LevelError {
+			if m.dedup(route, r.URL.Path, ww.status) {
+				return
+			}
 		}
 
-		for _, fn := range m.extractors {
-			attrs = append(attrs, fn(ctx)...)
+		attrs = append(attrs, m.deployment...)
+		if m.instanceName != "" {
+			attrs = append(attrs, slog.String("middleware.instance", m.instanceName))
 		}
+		attrs = append(attrs, m.versionInfo...)
 
-		m.logger.LogAttrs(
-			ctx,
-			m.leveler(ww.status),
-			fmt.Sprintf("%s %s [%d]", r.Method, r.URL.Path, ww.status),
-			attrs...,
-		)
+		if m.goroutines {
+			attrs = append(attrs, slog.Int("runtime.goroutines", runtime.NumGoroutine()))
+		}
+
+		if len(timing) > 0 {
+			attrs = append(attrs, slog.Attr{Key: m.timingGroup, Value: slog.GroupValue(timing...)})
+		}
+
+		if ww.pushes > 0 {
+			attrs = append(attrs, slog.Bool("http.pushed", true))
+		}
+
+		if ww.locked {
+			attrs = append(attrs, slog.Bool("http.status_locked", true))
+		}
+
+		if ww.hijacked {
+			attrs = append(attrs, slog.Bool("http.hijacked", true))
+		}
+
+		if state.handlerName != "" {
+			attrs = append(attrs, slog.String("handler.name", state.handlerName))
+		}
+
+		extracted := m.extractAll(ctx)
+		if m.extractorGroup != "" && len(extracted) > 0 {
+			attrs = append(attrs, slog.Attr{Key: m.extractorGroup, Value: slog.GroupValue(extracted...)})
+		} else {
+			attrs = append(attrs, extracted...)
+		}
+
+		if len(m.attrOrder) > 0 {
+			attrs = orderAttrs(attrs, m.attrOrder)
+		}
+
+		var msg string
+		if m.messageFunc != nil {
+			msg = m.messageFunc(attrs)
+		} else if !m.noMessage {
+			msg = fmt.Sprintf("%s %s [%d]", r.Method, path, ww.status)
+		}
+
+		m.logger.LogAttrs(ctx, level, msg, attrs...)
 	}()
 
+	if h := m.router(); h != nil {
+		_, route = h.Handler(r)
+		state.route = route
+		routed = true
+	}
+
+	m.target.ServeHTTP(ww.writer(), r)
+}
+
+// router returns the [http.ServeMux] that resolves routes, either the target
+// itself or the one given with [WithMux].
+func (m *Middleware) router() *http.ServeMux {
 	if h, ok := m.target.(*http.ServeMux); ok {
-		handler, pattern := h.Handler(r)
-		route = pattern
-		handler.ServeHTTP(ww, r)
-	} else {
-		m.target.ServeHTTP(ww, r)
+		return h
 	}
+	return m.mux
 }
 
 func (m *Middleware) filterPath(path string) bool {
+	if len(m.allowedPaths) > 0 {
+		return !m.allowPath(path)
+	}
 	_, ok := m.filteredPaths[path]
 	return ok
 }
 
+func (m *Middleware) allowPath(path string) bool {
+	for _, allowed := range m.allowedPaths {
+		if path == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(path, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *Middleware) filterRoute(route string) bool {
 	_, ok := m.filteredRoutes[route]
 	return ok
 }
 
+// warmup counts a logged request against the route's warmup limit and reports
+// whether the request is still within it.
+func (m *Middleware) warmup(route string) bool {
+	m.warmupMu.Lock()
+	defer m.warmupMu.Unlock()
+
+	if m.warmupCounts[route] >= m.warmupLimit {
+		return false
+	}
+	m.warmupCounts[route]++
+	return true
+}
+
+// contentLengthMismatch reports whether the handler declared a Content-Length
+// that differs from the number of bytes it wrote. Responses that never have a
+// body are not checked.
+func contentLengthMismatch(r *http.Request, ww *wrappedWriter) bool {
+	if r.Method == http.MethodHead || ww.status < 200 || ww.status == http.StatusNoContent || ww.status == http.StatusNotModified {
+		return false
+	}
+
+	declared, err := strconv.ParseInt(ww.Header().Get("Content-Length"), 10, 64)
+	if err != nil || declared < 0 {
+		return false
+	}
+	return declared != ww.bytes
+}
+
+// orderAttrs returns attrs with the attributes named in order first, in that
+// order, followed by the rest in their original order.
+func orderAttrs(attrs []slog.Attr, order []string) []slog.Attr {
+	ordered := make([]slog.Attr, 0, len(attrs))
+	used := make([]bool, len(attrs))
+	for _, key := range order {
+		for i, a := range attrs {
+			if !used[i] && a.Key == key {
+				ordered = append(ordered, a)
+				used[i] = true
+				break
+			}
+		}
+	}
+	for i, a := range attrs {
+		if !used[i] {
+			ordered = append(ordered, a)
+		}
+	}
+	return ordered
+}
+
+// statusClass returns the class of status, e.g. "4xx", or "unknown" if no
+// valid status was written.
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// outcome classifies a request for [WithOutcome]. An error on the request
+// context takes precedence over the status.
+func outcome(ctx context.Context, status int) string {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case status >= 500:
+		return "server_error"
+	case status >= 400:
+		return "client_error"
+	}
+	return "success"
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis,
+// and reports whether it did so.
+func truncate(s string, n int) (string, bool) {
+	runes := 0
+	for i := range s {
+		if runes == n {
+			return s[:i] + "…", true
+		}
+		runes++
+	}
+	return s, false
+}
+
+// headerValue applies [WithMaxHeaderValueLength] to a header value that is
+// about to be logged.
+func (m *Middleware) headerValue(v string) string {
+	if m.maxHeaderLength > 0 {
+		v, _ = truncate(v, m.maxHeaderLength)
+	}
+	return v
+}
+
+// levelReason names the rule that set the level for status before any
+// request-specific adjustments, for [WithLevelDiagnostics].
+func (m *Middleware) levelReason(status int) string {
+	if m.baseLevel != nil && (m.baseLevelMode == BaseLevelOverride || *m.baseLevel > m.statusLeveler(status)) {
+		return "base_level"
+	}
+	switch {
+	case status >= 500:
+		return "status_5xx"
+	case status >= 400:
+		return "status_4xx"
+	}
+	return "status"
+}
+
+// formAttrs returns the fields given with [WithFormFields] from the form the
+// handler parsed, or from the URL query if it parsed none.
+func (m *Middleware) formAttrs(r *http.Request) []slog.Attr {
+	form := r.Form
+	if form == nil {
+		form = r.URL.Query()
+	}
+	var attrs []slog.Attr
+	for _, name := range m.formFields {
+		if values, ok := form[name]; ok && len(values) > 0 {
+			attrs = append(attrs, slog.String("http.form."+name, m.headerValue(values[0])))
+		}
+	}
+	return attrs
+}
+
+// sanitize removes control characters, such as newlines, from s, and reports
+// whether there were any.
+func sanitize(s string) (string, bool) {
+	if !strings.ContainsFunc(s, unicode.IsControl) {
+		return s, false
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s), true
+}
+
+// cacheHit interprets a cache status header value such as "HIT" or
+// "Miss from cloudfront".
+func cacheHit(status string) (hit, ok bool) {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	switch {
+	case strings.HasPrefix(status, "HIT"):
+		return true, true
+	case strings.HasPrefix(status, "MISS"):
+		return false, true
+	}
+	return false, false
+}
+
+// depth returns the number of non-empty segments of path.
+func depth(path string) int {
+	n := 0
+	for seg := range strings.SplitSeq(path, "/") {
+		if seg != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// splitPattern separates an [http.ServeMux] pattern into its method and the
+// remaining host and path. Patterns without a method return an empty method.
+func splitPattern(pattern string) (method, path string) {
+	i := strings.IndexAny(pattern, " \t")
+	if i < 0 {
+		return "", pattern
+	}
+	return pattern[:i], strings.TrimLeft(pattern[i+1:], " \t")
+}
+
+// requestURL reconstructs the full URL of r as the client requested it, and
+// reports whether it was truncated to [WithMaxPathLength].
+func (m *Middleware) requestURL(r *http.Request) (string, bool) {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     r.Host,
+		Path:     r.URL.Path,
+		RawPath:  r.URL.RawPath,
+		RawQuery: r.URL.RawQuery,
+	}
+	if m.noURLQuery {
+		u.RawQuery = ""
+	}
+	if r.TLS != nil {
+		u.Scheme = "https"
+	}
+
+	if m.trustForwarded {
+		if proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ","); proto != "" {
+			u.Scheme = strings.TrimSpace(proto)
+		}
+		if host, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Host"), ","); host != "" {
+			u.Host = strings.TrimSpace(host)
+		}
+	}
+
+	if m.maxPathLength > 0 {
+		return truncate(u.String(), m.maxPathLength)
+	}
+	return u.String(), false
+}
+
+// serverAddrAttrs converts a host:port address into server.address and
+// server.port attributes, omitting any part that is missing.
+func serverAddrAttrs(addr string) []slog.Attr {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return []slog.Attr{slog.String("server.address", addr)}
+	}
+
+	var attrs []slog.Attr
+	if host != "" {
+		attrs = append(attrs, slog.String("server.address", host))
+	}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, slog.Int("server.port", p))
+	}
+	return attrs
+}
+
 // Options configure a [Middleware] instance.
 type Option func(mw *Middleware)
 
@@ -161,6 +937,16 @@ func WithPathFilter(paths ...string) Option {
 	}
 }
 
+// WithPathAllowlist only logs requests to the given paths and suppresses all
+// others. Paths ending in a slash also match every path below them, so "/api/"
+// matches "/api/users". The allowlist takes precedence over [WithPathFilter];
+// [New] rejects using both.
+func WithPathAllowlist(paths ...string) Option {
+	return func(mw *Middleware) {
+		mw.allowedPaths = append(mw.allowedPaths, paths...)
+	}
+}
+
 // WithRouteFilter excludes certain route patterns from an [http.ServeMux] from
 // access logging. Uses [http.ServeMux.Handler] to determine the pattern, so
 // the ignored routes should match those patterns.
@@ -187,3 +973,479 @@ func WithLeveler(fn Leveler) Option {
 		mw.leveler = fn
 	}
 }
+
+// WithSplitRoute logs the method and path of an [http.ServeMux] pattern
+// separately: "GET /foo/{id}" is logged as http.route="/foo/{id}" and
+// http.route_method="GET". Patterns without a method only set http.route.
+func WithSplitRoute() Option {
+	return func(mw *Middleware) {
+		mw.splitRoute = true
+	}
+}
+
+// WithServerAddr adds server.address and server.port attributes describing
+// the listener that served the request, e.g. to tell an admin port from a
+// public one. If addr is empty, the address is read from
+// [http.LocalAddrContextKey] on each request instead.
+func WithServerAddr(addr string) Option {
+	return func(mw *Middleware) {
+		mw.serverAddr = true
+		mw.serverAttrs = nil
+		if addr != "" {
+			mw.serverAttrs = serverAddrAttrs(addr)
+		}
+	}
+}
+
+// WithWarmupLogging only logs the first n requests to each matched
+// [http.ServeMux] route and suppresses the rest, e.g. to see the first few
+// requests after a cold start. Requests without a matched route share a
+// single count.
+func WithWarmupLogging(n int) Option {
+	return func(mw *Middleware) {
+		mw.warmupLimit = n
+		mw.warmupCounts = make(map[string]int)
+	}
+}
+
+// WithContentLengthCheck flags responses whose declared Content-Length does
+// not match the number of bytes the handler wrote, which usually indicates a
+// handler bug. Mismatched responses get an http.content_length_mismatch
+// attribute and are logged at [slog.LevelWarn] or higher.
+func WithContentLengthCheck() Option {
+	return func(mw *Middleware) {
+		mw.checkLength = true
+	}
+}
+
+// WithOTelSemconv names the built-in attributes after the OpenTelemetry HTTP
+// semantic conventions, e.g. http.response.status_code instead of
+// http.status_code, url.path instead of http.path and http.request.body.size
+// and http.response.body.size instead of http.request_size and
+// http.response_size, for compatibility with OpenTelemetry-based log
+// processing.
+func WithOTelSemconv() Option {
+	return func(mw *Middleware) {
+		mw.keys = otelKeys
+	}
+}
+
+// WithRetryHeader reads a client's retry count from the named request header,
+// e.g. "X-Retry-Count", and logs it as http.retry_count along with an
+// http.is_retry flag. Missing or non-numeric headers are ignored.
+func WithRetryHeader(name string) Option {
+	return func(mw *Middleware) {
+		mw.retryHeader = name
+	}
+}
+
+// WithMaxPathLength truncates logged paths longer than n runes, e.g. to keep
+// abusive kilobyte-long URLs from bloating logs. Truncated paths end in an
+// ellipsis and are marked with an http.path_truncated attribute.
+func WithMaxPathLength(n int) Option {
+	return func(mw *Middleware) {
+		mw.maxPathLength = n
+	}
+}
+
+// WithoutRequestSize omits the request's Content-Length, which is otherwise
+// logged as http.request_size when it is known.
+func WithoutRequestSize() Option {
+	return func(mw *Middleware) {
+		mw.noRequestSize = true
+	}
+}
+
+// WithChunkedRequestSize logs the number of body bytes the handler read as
+// http.request_size for requests without a Content-Length, e.g. with a
+// chunked body, by counting them as they are read. Bytes the handler does not
+// read are not counted.
+func WithChunkedRequestSize() Option {
+	return func(mw *Middleware) {
+		mw.chunkedSize = true
+	}
+}
+
+// WithoutResponseSize omits the number of response body bytes written by the
+// handler, which is otherwise logged as http.response_size.
+func WithoutResponseSize() Option {
+	return func(mw *Middleware) {
+		mw.noResponseSize = true
+	}
+}
+
+// WithoutMessage leaves the log message empty, for setups that rely only on
+// the structured attributes.
+func WithoutMessage() Option {
+	return func(mw *Middleware) {
+		mw.noMessage = true
+	}
+}
+
+// WithStatusClassLevels sets the log level by status class, keyed by the
+// hundreds digit of the status code, e.g. {4: slog.LevelWarn} logs all 4xx
+// responses at [slog.LevelWarn]. Classes that are not in the map use the
+// default [Leveler]. It replaces any [Leveler] set by [WithLeveler].
+func WithStatusClassLevels(levels map[int]slog.Level) Option {
+	levels = maps.Clone(levels)
+	return func(mw *Middleware) {
+		mw.leveler = func(status int) slog.Level {
+			if level, ok := levels[status/100]; ok {
+				return level
+			}
+			return defaultLeveler(status)
+		}
+	}
+}
+
+// WithAccept logs the request's Accept header as http.request.accept, to help
+// diagnose content negotiation problems. Requests without the header do not
+// get the attribute.
+func WithAccept() Option {
+	return func(mw *Middleware) {
+		mw.logAccept = true
+	}
+}
+
+// WithFormFields logs the named form fields as http.form.<name>, e.g.
+// http.form.page, to help debug form submissions. Only list fields that are
+// safe to log. To avoid consuming a body the handler needs, the Middleware
+// never parses the request body itself: it uses the form the handler parsed,
+// e.g. with [http.Request.ParseForm] or [http.Request.FormValue], or otherwise
+// only the URL query. Only the first value of each field is logged, and
+// fields that are absent are not.
+func WithFormFields(names ...string) Option {
+	return func(mw *Middleware) {
+		mw.formFields = slices.Clone(names)
+	}
+}
+
+// WithSafeExtractors recovers from panics in [ContextExtractor] functions.
+// Instead of failing the request, a warning with the extractor's index and the
+// panic value is logged, and the access log is recorded without that
+// extractor's attributes.
+func WithSafeExtractors() Option {
+	return func(mw *Middleware) {
+		mw.safeExtractors = true
+	}
+}
+
+// WithInitialBudget logs the time between the start of the request and the
+// deadline of its context as http.initial_budget, for requests that already
+// have a deadline when they reach the [Middleware].
+func WithInitialBudget() Option {
+	return func(mw *Middleware) {
+		mw.initialBudget = true
+	}
+}
+
+// WithExtractorGroup nests all attributes returned by [ContextExtractor]
+// functions in a single group with the given name, keeping them apart from the
+// built-in attributes.
+func WithExtractorGroup(name string) Option {
+	return func(mw *Middleware) {
+		mw.extractorGroup = name
+	}
+}
+
+// WithDefaultNotFound marks 404 responses that came from the default handler
+// of an [http.ServeMux], because no pattern matched, with an
+// http.not_found_default attribute. This distinguishes them from 404s written
+// by a matched handler.
+func WithDefaultNotFound() Option {
+	return func(mw *Middleware) {
+		mw.defaultNotFound = true
+	}
+}
+
+// WithBodyFirstRead logs the time from the start of the request until the
+// handler first reads the request body as http.body_first_read, which can
+// point to backpressure on upload-heavy endpoints. Requests whose body is
+// never read do not get the attribute.
+func WithBodyFirstRead() Option {
+	return func(mw *Middleware) {
+		mw.bodyFirstRead = true
+	}
+}
+
+// WithMessageFromAttrs builds the log message by calling fn with all of the
+// record's attributes, including those from [ContextExtractor] functions, in
+// place of the default "METHOD /path [status]". fn runs for every logged
+// request, so scanning the attributes should be kept cheap. It takes
+// precedence over [WithoutMessage].
+func WithMessageFromAttrs(fn func(attrs []slog.Attr) string) Option {
+	return func(mw *Middleware) {
+		mw.messageFunc = fn
+	}
+}
+
+// WithFullURL logs the URL of the request as the client requested it,
+// including scheme, host and query string, as http.url. The query string is
+// logged as is, without redaction, so use [WithoutURLQuery] if it may carry
+// tokens or personal data. With [WithMaxPathLength], the URL is truncated to
+// the same length as the path, and marked with an http.url_truncated
+// attribute when it is.
+func WithFullURL() Option {
+	return func(mw *Middleware) {
+		mw.fullURL = true
+	}
+}
+
+// WithoutURLQuery leaves the query string out of the URL logged by
+// [WithFullURL].
+func WithoutURLQuery() Option {
+	return func(mw *Middleware) {
+		mw.noURLQuery = true
+	}
+}
+
+// WithForwardedHeaders trusts the X-Forwarded-Proto and X-Forwarded-Host
+// headers when reconstructing the URL for [WithFullURL]. Only use it behind a
+// proxy that sets or strips these headers.
+func WithForwardedHeaders() Option {
+	return func(mw *Middleware) {
+		mw.trustForwarded = true
+	}
+}
+
+// WithCanceledLevel logs requests whose context was canceled by the time the
+// handler returned, usually because the client went away, at the given level
+// regardless of their status. This keeps client cancellations from being
+// logged, and alerted on, as server errors.
+func WithCanceledLevel(level slog.Level) Option {
+	return func(mw *Middleware) {
+		mw.canceledLevel = &level
+	}
+}
+
+// WithAttrOrder emits the attributes with the given keys first, in the given
+// order, followed by all other attributes in their default order. This keeps
+// output stable for positional log parsers. Keys must match the emitted keys,
+// e.g. url.path rather than http.path with [WithOTelSemconv].
+func WithAttrOrder(keys ...string) Option {
+	return func(mw *Middleware) {
+		mw.attrOrder = append(mw.attrOrder, keys...)
+	}
+}
+
+// WithTimingGroup nests the timing attributes, such as the duration and
+// http.body_first_read, in a single group with the given name rather than
+// logging them at the top level.
+func WithTimingGroup(name string) Option {
+	return func(mw *Middleware) {
+		mw.timingGroup = name
+	}
+}
+
+// WithGoroutineCount logs the number of goroutines when each request completes
+// as runtime.goroutines, a coarse signal for spotting leaks tied to particular
+// endpoints. Counting goroutines has a small cost, so this is intended for
+// diagnostic builds rather than normal use.
+func WithGoroutineCount() Option {
+	return func(mw *Middleware) {
+		mw.goroutines = true
+	}
+}
+
+// WithPanicOnly turns the middleware into a panic net for services that do
+// their own access logging: only requests whose handler panics are logged, at
+// Error and with the panic value as http.panic, and all others are silent.
+// After logging, the panic is re-raised so that the server still handles it
+// as usual, though the stack trace then starts at the middleware.
+func WithPanicOnly() Option {
+	return func(mw *Middleware) {
+		mw.panicOnly = true
+	}
+}
+
+// WithStatusClass logs the class of the response status, e.g. "2xx" or
+// "5xx", as http.status_class, a low-cardinality attribute for grouping.
+// Requests where no status was written get "unknown".
+func WithStatusClass() Option {
+	return func(mw *Middleware) {
+		mw.statusClass = true
+	}
+}
+
+// WithOutcome logs the outcome of the request as http.outcome, one of
+// "success", "client_error", "server_error", "timeout" or "canceled", a
+// single dimension for SLO counting. A request whose context timed out or
+// was canceled gets "timeout" or "canceled" regardless of its status.
+func WithOutcome() Option {
+	return func(mw *Middleware) {
+		mw.outcome = true
+	}
+}
+
+// WithDeploymentInfo attaches deployment metadata read from the environment,
+// such as the version or region, to every record. info maps attribute names,
+// e.g. "deployment.version", to the names of the environment variables that
+// hold their values. The variables are read once, when the middleware is
+// created, and those that are unset are omitted.
+func WithDeploymentInfo(info map[string]string) Option {
+	return func(mw *Middleware) {
+		mw.deployment = nil
+		for _, key := range slices.Sorted(maps.Keys(info)) {
+			if value, ok := os.LookupEnv(info[key]); ok {
+				mw.deployment = append(mw.deployment, slog.String(key, value))
+			}
+		}
+	}
+}
+
+// WithInstanceName logs name as middleware.instance on every record, to tell
+// apart the routers of a process that serves several, such as a public API,
+// an internal API and an admin interface, each wrapped separately.
+func WithInstanceName(name string) Option {
+	return func(mw *Middleware) {
+		mw.instanceName = name
+	}
+}
+
+// WithLogControlHeader lets handlers override whether a request is logged by
+// setting the named response header to "true" or "false". A true value logs
+// the request even if it would have been filtered or sampled out, and a false
+// value skips it. The header is removed before the response is sent, so it
+// must be set before the handler writes the status or the body.
+func WithLogControlHeader(name string) Option {
+	return func(mw *Middleware) {
+		mw.controlHeader = name
+	}
+}
+
+// WithVersionInfo attaches the Go version the binary was built with as
+// go.version and, when the build info records it, the version of the main
+// module as go.module.version to every record. Both are resolved once, when
+// the middleware is created. Development builds have no module version.
+func WithVersionInfo() Option {
+	return func(mw *Middleware) {
+		mw.versionInfo = []slog.Attr{slog.String("go.version", runtime.Version())}
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			mw.versionInfo = append(mw.versionInfo, slog.String("go.module.version", info.Main.Version))
+		}
+	}
+}
+
+// WithSequence logs a per-process sequence number as http.seq, starting from
+// 1 and incremented atomically as each request arrives. It orders records
+// whose timestamps collide at low resolution.
+func WithSequence() Option {
+	return func(mw *Middleware) {
+		mw.sequence = true
+	}
+}
+
+// WithMethodOverrideHeader logs the method requested with the named header,
+// e.g. "X-HTTP-Method-Override", as http.method_effective, next to the wire
+// method in http.method. The middleware only observes the header; it is up to
+// the application to honor it.
+func WithMethodOverrideHeader(name string) Option {
+	return func(mw *Middleware) {
+		mw.overrideHeader = name
+	}
+}
+
+// WithMux resolves the route of each request with mux when the wrapped
+// handler is not itself an [http.ServeMux], e.g. because auth or rate
+// limiting middleware sits between this middleware and the mux. The route is
+// resolved before the wrapped handler runs, so requests that are rejected
+// before they reach mux still get http.route. Handlers in between must not
+// rewrite the request in ways that change which pattern it matches.
+func WithMux(mux *http.ServeMux) Option {
+	return func(mw *Middleware) {
+		mw.mux = mux
+	}
+}
+
+// WithMaxHeaderValueLength truncates the values of headers logged by other
+// options, such as [WithAccept], to at most n characters, marking truncated
+// values with a trailing ellipsis. This keeps a pathological header, e.g. a
+// giant token, from bloating the logs.
+func WithMaxHeaderValueLength(n int) Option {
+	return func(mw *Middleware) {
+		mw.maxHeaderLength = n
+	}
+}
+
+// WithSanitizedPath removes control characters, such as newlines, from the
+// logged path, which keep a crafted URL from forging log lines. When this
+// changes the path, the percent-escaped original is logged as http.path_raw
+// for forensics, and the record is flagged with http.path_suspicious=true.
+func WithSanitizedPath() Option {
+	return func(mw *Middleware) {
+		mw.sanitizePath = true
+	}
+}
+
+// WithContentEncoding logs the Content-Encoding of the response, e.g. "gzip",
+// as http.response.content_encoding, to put response sizes in context.
+// Responses without an encoding, or with the identity encoding, do not get
+// the attribute.
+func WithContentEncoding() Option {
+	return func(mw *Middleware) {
+		mw.contentEncoding = true
+	}
+}
+
+// WithAllowedMethods logs the Allow header of 405 Method Not Allowed
+// responses, such as those from an [http.ServeMux], as http.allowed_methods,
+// to debug mismatches between clients and routes.
+func WithAllowedMethods() Option {
+	return func(mw *Middleware) {
+		mw.allowedMethods = true
+	}
+}
+
+// WithBaseLevel sets a level for all requests regardless of status, e.g. to
+// run access logs at Debug in development. With [BaseLevelOverride], every
+// request is logged at level, and with [BaseLevelFloor], at level or the
+// level from the [Leveler], whichever is higher. Options that raise the level
+// of specific requests, such as [WithContentLengthCheck], still apply.
+func WithBaseLevel(level slog.Level, mode BaseLevelMode) Option {
+	return func(mw *Middleware) {
+		mw.baseLevel = &level
+		mw.baseLevelMode = mode
+	}
+}
+
+// WithLevelDiagnostics logs the rule that decided the level of each record
+// as log.level_reason, to help tune leveling options. The reasons are
+// "status", "status_4xx" and "status_5xx" for the [Leveler], "base_level" for
+// [WithBaseLevel] when it wins, "content_length_mismatch" for
+// [WithContentLengthCheck], "write_error" when writing the response failed,
+// "canceled" for [WithCanceledLevel] and "panic" for [WithPanicOnly].
+func WithLevelDiagnostics() Option {
+	return func(mw *Middleware) {
+		mw.levelDiag = true
+	}
+}
+
+// WithCacheStatusHeader logs the value of the named response header, e.g.
+// "X-Cache", as http.cache_status. Values that start with "HIT" or "MISS",
+// ignoring case, are also logged as http.cache_hit=true or false.
+func WithCacheStatusHeader(name string) Option {
+	return func(mw *Middleware) {
+		mw.cacheHeader = name
+	}
+}
+
+// WithRouteDepth logs the number of segments in the path of the matched
+// route pattern as http.route_depth, e.g. 3 for "GET /a/b/{c}", which helps
+// diagnose wildcard routing. Requests that match no route get no attribute,
+// unless usePath is true, in which case the depth of the request path is
+// logged instead.
+func WithRouteDepth(usePath bool) Option {
+	return func(mw *Middleware) {
+		mw.routeDepth = true
+		mw.pathDepth = usePath
+	}
+}
+
+// WithBandwidth logs the size of the response body divided by the duration
+// of the request, in bytes per second, as http.bandwidth_bytes_per_sec, to
+// spot slow clients and large transfers.
+func WithBandwidth() Option {
+	return func(mw *Middleware) {
+		mw.bandwidth = true
+	}
+}
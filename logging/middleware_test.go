@@ -2,11 +2,15 @@ package logging_test
 
 import (
 	"context"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"runtime"
 	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -154,6 +158,66 @@ func TestMiddleware_WithPathFilter(t *testing.T) {
 	}
 }
 
+func TestMiddleware_WithPathAllowlist(t *testing.T) {
+	f := func(allowlist []string, path string, shouldLog bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithPathAllowlist(allowlist...))
+
+			mw.ServeHTTP(rr, r)
+
+			if shouldLog {
+				assert.NotEmpty(t, th.records)
+			} else {
+				assert.Empty(t, th.records)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		allowlist []string
+		path      string
+		shouldLog bool
+	}{
+		{
+			name:      "logs listed path",
+			allowlist: []string{"/checkout"},
+			path:      "/checkout",
+			shouldLog: true,
+		},
+		{
+			name:      "ignores unlisted path",
+			allowlist: []string{"/checkout"},
+			path:      "/healthz",
+			shouldLog: false,
+		},
+		{
+			name:      "does not match by prefix without trailing slash",
+			allowlist: []string{"/checkout"},
+			path:      "/checkout/confirm",
+			shouldLog: false,
+		},
+		{
+			name:      "matches by prefix with trailing slash",
+			allowlist: []string{"/api/"},
+			path:      "/api/users",
+			shouldLog: true,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.allowlist, tc.path, tc.shouldLog))
+	}
+}
+
 func TestMiddleware_WithRouteFilter(t *testing.T) {
 	f := func(filter []string, path string, shouldLog bool) func(*testing.T) {
 		return func(t *testing.T) {
@@ -275,6 +339,2090 @@ func TestMiddleware_WithLeveler(t *testing.T) {
 	}
 }
 
+func TestMiddleware_WithSplitRoute(t *testing.T) {
+	f := func(pattern, path, expectedRoute, expectedMethod string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc(pattern, http.NotFound)
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithSplitRoute())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, expectedRoute, attrs["http.route"].Value.String())
+			if expectedMethod != "" {
+				assert.Equal(t, expectedMethod, attrs["http.route_method"].Value.String())
+			} else {
+				assert.NotContains(t, attrs, "http.route_method")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		pattern string
+		path    string
+		route   string
+		method  string
+	}{
+		{
+			name:    "pattern with method",
+			pattern: "GET /foo/{id}",
+			path:    "/foo/1234",
+			route:   "/foo/{id}",
+			method:  http.MethodGet,
+		},
+		{
+			name:    "pattern without method",
+			pattern: "/legacy",
+			path:    "/legacy",
+			route:   "/legacy",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.pattern, tc.path, tc.route, tc.method))
+	}
+}
+
+func TestMiddleware_WithServerAddr(t *testing.T) {
+	f := func(addr string, localAddr net.Addr, expectedHost string, expectedPort int64) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			ctx := context.Background()
+			if localAddr != nil {
+				ctx = context.WithValue(ctx, http.LocalAddrContextKey, localAddr)
+			}
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithServerAddr(addr))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, expectedHost, attrs["server.address"].Value.String())
+			assert.Equal(t, expectedPort, attrs["server.port"].Value.Int64())
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		addr      string
+		localAddr net.Addr
+		host      string
+		port      int64
+	}{
+		{
+			name: "explicit address",
+			addr: "10.0.0.1:9090",
+			host: "10.0.0.1",
+			port: 9090,
+		},
+		{
+			name:      "explicit address wins over context",
+			addr:      "10.0.0.1:9090",
+			localAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080},
+			host:      "10.0.0.1",
+			port:      9090,
+		},
+		{
+			name:      "address from context",
+			localAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080},
+			host:      "127.0.0.1",
+			port:      8080,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.addr, tc.localAddr, tc.host, tc.port))
+	}
+}
+
+func TestMiddleware_WithWarmupLogging(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /foo", http.NotFound)
+	mux.HandleFunc("GET /bar", http.NotFound)
+
+	limit := 2
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithWarmupLogging(limit))
+
+	for range limit + 1 {
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		mw.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	assert.Len(t, th.records, limit)
+
+	r := httptest.NewRequest(http.MethodGet, "/bar", nil)
+	mw.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Len(t, th.records, limit+1)
+}
+
+func TestNew(t *testing.T) {
+	f := func(opts []logging.Option, expectedErr string) func(*testing.T) {
+		return func(t *testing.T) {
+			h, err := logging.New(http.NewServeMux(), opts...)
+			if expectedErr == "" {
+				assert.NoError(t, err)
+				assert.NotNil(t, h)
+			} else {
+				assert.ErrorContains(t, err, expectedErr)
+				assert.Nil(t, h)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name string
+		opts []logging.Option
+		err  string
+	}{
+		{
+			name: "valid options",
+			opts: []logging.Option{logging.WithLogger(slog.Default()), logging.WithWarmupLogging(5)},
+		},
+		{
+			name: "nil logger",
+			opts: []logging.Option{logging.WithLogger(nil)},
+			err:  "logger is nil",
+		},
+		{
+			name: "path allowlist and filter",
+			opts: []logging.Option{logging.WithPathAllowlist("/api/"), logging.WithPathFilter("/healthz")},
+			err:  "path allowlist and path filter are mutually exclusive",
+		},
+		{
+			name: "sample rate out of range",
+			opts: []logging.Option{logging.WithSampleRate(1.5)},
+			err:  "sample rate 1.5 is not between 0 and 1",
+		},
+		{
+			name: "non-positive warmup limit",
+			opts: []logging.Option{logging.WithWarmupLogging(0)},
+			err:  "warmup limit 0 is not positive",
+		},
+		{
+			name: "nil raw output writer",
+			opts: []logging.Option{logging.WithRawOutput(nil, nil)},
+			err:  "raw output writer is nil",
+		},
+		{
+			name: "body capture rate out of range",
+			opts: []logging.Option{logging.WithBodyCaptureSampling(2, 100)},
+			err:  "body capture rate 2 is not between 0 and 1",
+		},
+		{
+			name: "non-positive body capture size",
+			opts: []logging.Option{logging.WithBodyCaptureSampling(0.1, 0)},
+			err:  "body capture size 0 is not positive",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.opts, tc.err))
+	}
+}
+
+func TestMiddleware_WithContentLengthCheck(t *testing.T) {
+	f := func(declared string, payload []byte, mismatch bool, expectedLevel slog.Level) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Length", declared)
+				w.Write(payload)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithContentLengthCheck())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if mismatch {
+				assert.True(t, attrs["http.content_length_mismatch"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "http.content_length_mismatch")
+			}
+			assert.Equal(t, expectedLevel, th.records[0].Level)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		declared string
+		payload  []byte
+		mismatch bool
+		level    slog.Level
+	}{
+		{
+			name:     "declared length matches",
+			declared: "5",
+			payload:  []byte("hello"),
+			level:    slog.LevelInfo,
+		},
+		{
+			name:     "declared length larger than written",
+			declared: "100",
+			payload:  []byte("hello"),
+			mismatch: true,
+			level:    slog.LevelWarn,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.declared, tc.payload, tc.mismatch, tc.level))
+	}
+}
+
+func TestMiddleware_WithOTelSemconv(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+
+	route := "GET /foo/{id}"
+	mux := http.NewServeMux()
+	mux.HandleFunc(route, http.NotFound)
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/foo/1234", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithOTelSemconv())
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, int64(http.StatusNotFound), attrs["http.response.status_code"].Value.Int64())
+	assert.Equal(t, http.MethodGet, attrs["http.request.method"].Value.String())
+	assert.Equal(t, "/foo/1234", attrs["url.path"].Value.String())
+	assert.Equal(t, route, attrs["http.route"].Value.String())
+	assert.NotContains(t, attrs, "http.status_code")
+	assert.NotContains(t, attrs, "http.method")
+	assert.NotContains(t, attrs, "http.path")
+}
+
+func TestMiddleware_WithRetryHeader(t *testing.T) {
+	f := func(value string, logged bool, expectedCount int64, expectedRetry bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if value != "" {
+				r.Header.Set("X-Retry-Count", value)
+			}
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithRetryHeader("X-Retry-Count"))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if logged {
+				assert.Equal(t, expectedCount, attrs["http.retry_count"].Value.Int64())
+				assert.Equal(t, expectedRetry, attrs["http.is_retry"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "http.retry_count")
+				assert.NotContains(t, attrs, "http.is_retry")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		value   string
+		logged  bool
+		count   int64
+		isRetry bool
+	}{
+		{
+			name:  "no header",
+			value: "",
+		},
+		{
+			name:   "first attempt",
+			value:  "0",
+			logged: true,
+		},
+		{
+			name:    "retried",
+			value:   "2",
+			logged:  true,
+			count:   2,
+			isRetry: true,
+		},
+		{
+			name:  "non-numeric header",
+			value: "lots",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.value, tc.logged, tc.count, tc.isRetry))
+	}
+}
+
+func TestMiddleware_WithMaxPathLength(t *testing.T) {
+	f := func(path, expectedPath string, truncated bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.URL.Path = path
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithMaxPathLength(8))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, expectedPath, attrs["http.path"].Value.String())
+			if truncated {
+				assert.True(t, attrs["http.path_truncated"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "http.path_truncated")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		path      string
+		expected  string
+		truncated bool
+	}{
+		{
+			name:     "short path",
+			path:     "/short",
+			expected: "/short",
+		},
+		{
+			name:     "path at limit",
+			path:     "/exactly",
+			expected: "/exactly",
+		},
+		{
+			name:      "long path",
+			path:      "/a/very/long/path",
+			expected:  "/a/very/…",
+			truncated: true,
+		},
+		{
+			name:      "multibyte runes",
+			path:      "/ünïcödé/path",
+			expected:  "/ünïcödé…",
+			truncated: true,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.path, tc.expected, tc.truncated))
+	}
+}
+
+func TestMiddleware_WithoutMessage(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithoutMessage())
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	assert.Empty(t, th.records[0].Message)
+	assert.Equal(t, 6, th.records[0].NumAttrs())
+}
+
+func TestMiddleware_WithStatusClassLevels(t *testing.T) {
+	f := func(statusCode int, expectedLevel slog.Level) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(statusCode)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithStatusClassLevels(map[int]slog.Level{
+				2: slog.LevelDebug,
+				4: slog.LevelWarn,
+			}))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			assert.Equal(t, expectedLevel, th.records[0].Level)
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		status int
+		level  slog.Level
+	}{
+		{
+			name:   "configured 2xx level",
+			status: http.StatusOK,
+			level:  slog.LevelDebug,
+		},
+		{
+			name:   "configured 4xx level",
+			status: http.StatusNotFound,
+			level:  slog.LevelWarn,
+		},
+		{
+			name:   "default 3xx level",
+			status: http.StatusFound,
+			level:  slog.LevelInfo,
+		},
+		{
+			name:   "default 5xx level",
+			status: http.StatusInternalServerError,
+			level:  slog.LevelError,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.status, tc.level))
+	}
+}
+
+func TestMiddleware_WithAccept(t *testing.T) {
+	f := func(accept string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if accept != "" {
+				r.Header.Set("Accept", accept)
+			}
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithAccept())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if accept != "" {
+				assert.Equal(t, accept, attrs["http.request.accept"].Value.String())
+			} else {
+				assert.NotContains(t, attrs, "http.request.accept")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		accept string
+	}{
+		{
+			name:   "with accept header",
+			accept: "application/json",
+		},
+		{
+			name: "without accept header",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.accept))
+	}
+}
+
+func TestMiddleware_WithSafeExtractors(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithSafeExtractors(), logging.WithContextExtractors(
+		func(ctx context.Context) []slog.Attr {
+			return []slog.Attr{slog.String("first", "ok")}
+		},
+		func(ctx context.Context) []slog.Attr {
+			val := ctx.Value("missing").(string)
+			return []slog.Attr{slog.String("second", val)}
+		},
+	))
+
+	assert.NotPanics(t, func() {
+		mw.ServeHTTP(rr, r)
+	})
+
+	assert.Len(t, th.records, 2)
+	assert.Equal(t, slog.LevelWarn, th.records[0].Level)
+	warnAttrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		warnAttrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, int64(1), warnAttrs["extractor"].Value.Int64())
+	assert.Contains(t, warnAttrs, "panic")
+
+	attrs := make(map[string]slog.Attr)
+	th.records[1].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, "ok", attrs["first"].Value.String())
+	assert.NotContains(t, attrs, "second")
+	assert.Equal(t, int64(http.StatusNotFound), attrs["http.status_code"].Value.Int64())
+}
+
+func TestMiddleware_WithInitialBudget(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	timeout := 2 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithInitialBudget())
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.InDelta(t, timeout, attrs["http.initial_budget"].Value.Duration(), float64(5*time.Millisecond))
+}
+
+func TestMiddleware_WithInitialBudget_NoDeadline(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithInitialBudget())
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.NotContains(t, attrs, "http.initial_budget")
+}
+
+func TestMiddleware_WithExtractorGroup(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithExtractorGroup("ctx"), logging.WithContextExtractors(
+		func(context.Context) []slog.Attr {
+			return []slog.Attr{slog.String("user", "alice")}
+		},
+		func(context.Context) []slog.Attr {
+			return []slog.Attr{slog.String("http.path", "shadowed")}
+		},
+	))
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, "/", attrs["http.path"].Value.String())
+	assert.NotContains(t, attrs, "user")
+
+	group := attrs["ctx"].Value.Group()
+	assert.Len(t, group, 2)
+	assert.Equal(t, "user", group[0].Key)
+	assert.Equal(t, "alice", group[0].Value.String())
+	assert.Equal(t, "http.path", group[1].Key)
+	assert.Equal(t, "shadowed", group[1].Value.String())
+}
+
+func TestMiddleware_WithDefaultNotFound(t *testing.T) {
+	f := func(path string, defaultNotFound bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /items/{id}", http.NotFound)
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithDefaultNotFound())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if defaultNotFound {
+				assert.True(t, attrs["http.not_found_default"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "http.not_found_default")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		path            string
+		defaultNotFound bool
+	}{
+		{
+			name: "handler returns 404",
+			path: "/items/1234",
+		},
+		{
+			name:            "no matching route",
+			path:            "/missing",
+			defaultNotFound: true,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.path, tc.defaultNotFound))
+	}
+}
+
+func TestMiddleware_WithMessageFromAttrs(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux,
+		logging.WithLogger(logger),
+		logging.WithContextExtractors(func(context.Context) []slog.Attr {
+			return []slog.Attr{slog.String("user", "alice")}
+		}),
+		logging.WithMessageFromAttrs(func(attrs []slog.Attr) string {
+			var method, user string
+			for _, a := range attrs {
+				switch a.Key {
+				case "http.method":
+					method = a.Value.String()
+				case "user":
+					user = a.Value.String()
+				}
+			}
+			return method + " by " + user
+		}),
+	)
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	assert.Equal(t, "GET by alice", th.records[0].Message)
+}
+
+func TestMiddleware_WithFullURL(t *testing.T) {
+	f := func(target string, tls bool, headers map[string]string, opts []logging.Option, expected string, truncated bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			if !tls {
+				r.TLS = nil
+			}
+			for k, v := range headers {
+				r.Header.Set(k, v)
+			}
+
+			mw := logging.Wrap(mux, append(opts, logging.WithLogger(logger), logging.WithFullURL())...)
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, expected, attrs["http.url"].Value.String())
+			if truncated {
+				assert.True(t, attrs["http.url_truncated"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "http.url_truncated")
+			}
+		}
+	}
+
+	forwarded := map[string]string{
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "public.example.com",
+	}
+
+	testCases := []struct {
+		name      string
+		target    string
+		tls       bool
+		headers   map[string]string
+		opts      []logging.Option
+		expected  string
+		truncated bool
+	}{
+		{
+			name:     "plain http",
+			target:   "http://example.com/foo?bar=baz",
+			expected: "http://example.com/foo?bar=baz",
+		},
+		{
+			name:     "tls",
+			target:   "https://example.com/foo",
+			tls:      true,
+			expected: "https://example.com/foo",
+		},
+		{
+			name:     "untrusted forwarded headers",
+			target:   "http://internal:8080/foo",
+			headers:  forwarded,
+			expected: "http://internal:8080/foo",
+		},
+		{
+			name:     "trusted forwarded headers",
+			target:   "http://internal:8080/foo",
+			headers:  forwarded,
+			opts:     []logging.Option{logging.WithForwardedHeaders()},
+			expected: "https://public.example.com/foo",
+		},
+		{
+			name:     "without query",
+			target:   "http://example.com/foo?token=secret",
+			opts:     []logging.Option{logging.WithoutURLQuery()},
+			expected: "http://example.com/foo",
+		},
+		{
+			name:      "truncated",
+			target:    "http://example.com/foo?bar=" + strings.Repeat("x", 1024),
+			opts:      []logging.Option{logging.WithMaxPathLength(24)},
+			expected:  "http://example.com/foo?b…",
+			truncated: true,
+		},
+		{
+			name:     "fits max length",
+			target:   "http://example.com/foo",
+			opts:     []logging.Option{logging.WithMaxPathLength(24)},
+			expected: "http://example.com/foo",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.target, tc.tls, tc.headers, tc.opts, tc.expected, tc.truncated))
+	}
+}
+
+func TestMiddleware_WithCanceledLevel(t *testing.T) {
+	f := func(cancelRequest bool, expectedLevel slog.Level) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				if cancelRequest {
+					cancel()
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithCanceledLevel(slog.LevelDebug))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			assert.Equal(t, expectedLevel, th.records[0].Level)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		canceled bool
+		level    slog.Level
+	}{
+		{
+			name:     "canceled request is downgraded",
+			canceled: true,
+			level:    slog.LevelDebug,
+		},
+		{
+			name:  "server error is not downgraded",
+			level: slog.LevelError,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.canceled, tc.level))
+	}
+}
+
+func TestMiddleware_WithAttrOrder(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /foo", http.NotFound)
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithAttrOrder("http.method", "http.path", "unknown", "http.status_code"))
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+
+	var keys []string
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		keys = append(keys, a.Key)
+		return true
+	})
+	assert.Equal(t, []string{"http.method", "http.path", "http.status_code", "duration", "http.request_size", "http.response_size", "http.route"}, keys)
+}
+
+func TestMiddleware_WithGoroutineCount(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithGoroutineCount())
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Positive(t, attrs["runtime.goroutines"].Value.Int64())
+}
+
+func TestMiddleware_WithPanicOnly(t *testing.T) {
+	f := func(path string, logged bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/ok", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			mux.HandleFunc("/panic", func(http.ResponseWriter, *http.Request) {
+				panic("boom")
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithPanicOnly())
+
+			if !logged {
+				mw.ServeHTTP(rr, r)
+				assert.Empty(t, th.records)
+				return
+			}
+
+			assert.PanicsWithValue(t, "boom", func() {
+				mw.ServeHTTP(rr, r)
+			})
+			assert.Len(t, th.records, 1)
+			assert.Equal(t, slog.LevelError, th.records[0].Level)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, "boom", attrs["http.panic"].Value.String())
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		path   string
+		logged bool
+	}{
+		{
+			name: "normal request is silent",
+			path: "/ok",
+		},
+		{
+			name:   "panicking request is logged",
+			path:   "/panic",
+			logged: true,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.path, tc.logged))
+	}
+}
+
+func TestMiddleware_WithStatusClass(t *testing.T) {
+	f := func(status int, expected string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				if status != 0 {
+					w.WriteHeader(status)
+				}
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithStatusClass())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, expected, attrs["http.status_class"].Value.String())
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		status   int
+		expected string
+	}{
+		{
+			name:     "no content",
+			status:   http.StatusNoContent,
+			expected: "2xx",
+		},
+		{
+			name:     "not found",
+			status:   http.StatusNotFound,
+			expected: "4xx",
+		},
+		{
+			name:     "service unavailable",
+			status:   http.StatusServiceUnavailable,
+			expected: "5xx",
+		},
+		{
+			name:     "nothing written",
+			expected: "unknown",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.status, tc.expected))
+	}
+}
+
+func TestMiddleware_WithDeploymentInfo(t *testing.T) {
+	t.Setenv("TEST_DEPLOYMENT_VERSION", "v1.2.3")
+	t.Setenv("TEST_DEPLOYMENT_REGION", "us-east-1")
+
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithDeploymentInfo(map[string]string{
+		"deployment.version": "TEST_DEPLOYMENT_VERSION",
+		"deployment.region":  "TEST_DEPLOYMENT_REGION",
+		"deployment.zone":    "TEST_DEPLOYMENT_ZONE_UNSET",
+	}))
+
+	// Changes after the middleware is created are not picked up.
+	t.Setenv("TEST_DEPLOYMENT_VERSION", "v2.0.0")
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, "v1.2.3", attrs["deployment.version"].Value.String())
+	assert.Equal(t, "us-east-1", attrs["deployment.region"].Value.String())
+	assert.NotContains(t, attrs, "deployment.zone")
+}
+
+func TestMiddleware_WithLogControlHeader(t *testing.T) {
+	f := func(path, value string, logged bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				if value != "" {
+					w.Header().Set("X-Log", value)
+				}
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte("ok"))
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+
+			mw := logging.Wrap(mux,
+				logging.WithLogger(logger),
+				logging.WithPathFilter("/healthcheck"),
+				logging.WithLogControlHeader("X-Log"),
+			)
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Empty(t, rr.Header().Values("X-Log"))
+			assert.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+			if logged {
+				assert.Len(t, th.records, 1)
+			} else {
+				assert.Empty(t, th.records)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		path   string
+		value  string
+		logged bool
+	}{
+		{
+			name:   "no header",
+			path:   "/",
+			logged: true,
+		},
+		{
+			name:  "force skip",
+			path:  "/",
+			value: "false",
+		},
+		{
+			name:   "force log filtered path",
+			path:   "/healthcheck",
+			value:  "true",
+			logged: true,
+		},
+		{
+			name: "filtered path without header",
+			path: "/healthcheck",
+		},
+		{
+			name:   "invalid value is ignored",
+			path:   "/",
+			value:  "maybe",
+			logged: true,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.path, tc.value, tc.logged))
+	}
+}
+
+func TestMiddleware_WithVersionInfo(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithVersionInfo())
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, runtime.Version(), attrs["go.version"].Value.String())
+}
+
+func TestMiddleware_WithSequence(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithSequence())
+
+	for range 2 {
+		mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	assert.Len(t, th.records, 2)
+	var seqs []uint64
+	for _, rec := range th.records {
+		rec.Attrs(func(a slog.Attr) bool {
+			if a.Key == "http.seq" {
+				seqs = append(seqs, a.Value.Uint64())
+			}
+			return true
+		})
+	}
+	assert.Equal(t, []uint64{1, 2}, seqs)
+}
+
+func TestMiddleware_WithMethodOverrideHeader(t *testing.T) {
+	f := func(override, expected string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			var method string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				method = r.Method
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if override != "" {
+				r.Header.Set("X-HTTP-Method-Override", override)
+			}
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithMethodOverrideHeader("X-HTTP-Method-Override"))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Equal(t, http.MethodPost, method)
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, http.MethodPost, attrs["http.method"].Value.String())
+			if expected != "" {
+				assert.Equal(t, expected, attrs["http.method_effective"].Value.String())
+			} else {
+				assert.NotContains(t, attrs, "http.method_effective")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		override string
+		expected string
+	}{
+		{
+			name:     "override to PUT",
+			override: "PUT",
+			expected: http.MethodPut,
+		},
+		{
+			name:     "lowercase override",
+			override: "delete",
+			expected: http.MethodDelete,
+		},
+		{
+			name: "no override",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.override, tc.expected))
+	}
+}
+
+func TestMiddleware_WithMux(t *testing.T) {
+	f := func(token string, expectedStatus int) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})
+			auth := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				mux.ServeHTTP(w, r)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/users/1234", nil)
+			r.Header.Set("Authorization", token)
+
+			mw := logging.Wrap(auth, logging.WithLogger(logger), logging.WithMux(mux))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Equal(t, expectedStatus, rr.Code)
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, int64(expectedStatus), attrs["http.status_code"].Value.Int64())
+			assert.Equal(t, "GET /users/{id}", attrs["http.route"].Value.String())
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{
+			name:   "authorized",
+			token:  "secret",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "rejected before routing",
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.token, tc.status))
+	}
+}
+
+func TestMiddleware_WithMaxHeaderValueLength(t *testing.T) {
+	f := func(accept, expected string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", accept)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithAccept(), logging.WithMaxHeaderValueLength(10))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, expected, attrs["http.request.accept"].Value.String())
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{
+			name:     "short value",
+			accept:   "text/html",
+			expected: "text/html",
+		},
+		{
+			name:     "long value",
+			accept:   "application/json, text/plain",
+			expected: "applicatio…",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.accept, tc.expected))
+	}
+}
+
+func TestMiddleware_WithSanitizedPath(t *testing.T) {
+	f := func(path, expected, raw string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.URL.Path = path
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithSanitizedPath())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			assert.Equal(t, "GET "+expected+" [404]", th.records[0].Message)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, expected, attrs["http.path"].Value.String())
+			if raw != "" {
+				assert.Equal(t, raw, attrs["http.path_raw"].Value.String())
+				assert.True(t, attrs["http.path_suspicious"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "http.path_raw")
+				assert.NotContains(t, attrs, "http.path_suspicious")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		expected string
+		raw      string
+	}{
+		{
+			name:     "clean path",
+			path:     "/users/1234",
+			expected: "/users/1234",
+		},
+		{
+			name:     "newline",
+			path:     "/users\nGET /admin [200]",
+			expected: "/usersGET /admin [200]",
+			raw:      "/users%0AGET%20/admin%20%5B200%5D",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.path, tc.expected, tc.raw))
+	}
+}
+
+func TestMiddleware_WithContentEncoding(t *testing.T) {
+	f := func(encoding, expected string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				if encoding != "" {
+					w.Header().Set("Content-Encoding", encoding)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithContentEncoding())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if expected != "" {
+				assert.Equal(t, expected, attrs["http.response.content_encoding"].Value.String())
+			} else {
+				assert.NotContains(t, attrs, "http.response.content_encoding")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		encoding string
+		expected string
+	}{
+		{
+			name:     "gzip",
+			encoding: "gzip",
+			expected: "gzip",
+		},
+		{
+			name:     "identity",
+			encoding: "identity",
+		},
+		{
+			name: "unset",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.encoding, tc.expected))
+	}
+}
+
+func TestMiddleware_WithAllowedMethods(t *testing.T) {
+	f := func(method string, expectedStatus int, expected string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /items", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(method, "/items", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithAllowedMethods())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Equal(t, expectedStatus, rr.Code)
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if expected != "" {
+				assert.Equal(t, expected, attrs["http.allowed_methods"].Value.String())
+			} else {
+				assert.NotContains(t, attrs, "http.allowed_methods")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		method   string
+		status   int
+		expected string
+	}{
+		{
+			name:     "method not allowed",
+			method:   http.MethodPost,
+			status:   http.StatusMethodNotAllowed,
+			expected: "GET, HEAD",
+		},
+		{
+			name:   "allowed",
+			method: http.MethodGet,
+			status: http.StatusOK,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.method, tc.status, tc.expected))
+	}
+}
+
+func TestMiddleware_WithBaseLevel(t *testing.T) {
+	f := func(base slog.Level, mode logging.BaseLevelMode, status int, expected slog.Level) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(status)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithBaseLevel(base, mode))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			assert.Equal(t, expected, th.records[0].Level)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		base     slog.Level
+		mode     logging.BaseLevelMode
+		status   int
+		expected slog.Level
+	}{
+		{
+			name:     "override lowers success",
+			base:     slog.LevelDebug,
+			mode:     logging.BaseLevelOverride,
+			status:   http.StatusOK,
+			expected: slog.LevelDebug,
+		},
+		{
+			name:     "override lowers server error",
+			base:     slog.LevelDebug,
+			mode:     logging.BaseLevelOverride,
+			status:   http.StatusInternalServerError,
+			expected: slog.LevelDebug,
+		},
+		{
+			name:     "floor raises success",
+			base:     slog.LevelWarn,
+			mode:     logging.BaseLevelFloor,
+			status:   http.StatusOK,
+			expected: slog.LevelWarn,
+		},
+		{
+			name:     "floor keeps higher status level",
+			base:     slog.LevelWarn,
+			mode:     logging.BaseLevelFloor,
+			status:   http.StatusInternalServerError,
+			expected: slog.LevelError,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.base, tc.mode, tc.status, tc.expected))
+	}
+}
+
+func TestMiddleware_WithCacheStatusHeader(t *testing.T) {
+	f := func(value string, hit *bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("X-Cache", value)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithCacheStatusHeader("X-Cache"))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, value, attrs["http.cache_status"].Value.String())
+			if hit != nil {
+				assert.Equal(t, *hit, attrs["http.cache_hit"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "http.cache_hit")
+			}
+		}
+	}
+
+	hit, miss := true, false
+
+	testCases := []struct {
+		name  string
+		value string
+		hit   *bool
+	}{
+		{
+			name:  "hit",
+			value: "HIT",
+			hit:   &hit,
+		},
+		{
+			name:  "miss",
+			value: "MISS",
+			hit:   &miss,
+		},
+		{
+			name:  "hit with details",
+			value: "Hit from cloudfront",
+			hit:   &hit,
+		},
+		{
+			name:  "other status",
+			value: "STALE",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.value, tc.hit))
+	}
+}
+
+func TestMiddleware_WithRouteDepth(t *testing.T) {
+	f := func(path string, usePath bool, expected int) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /a/b/{c}", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			mux.HandleFunc("example.com/files/{rest...}", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithRouteDepth(usePath))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if expected >= 0 {
+				assert.Equal(t, int64(expected), attrs["http.route_depth"].Value.Int64())
+			} else {
+				assert.NotContains(t, attrs, "http.route_depth")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		usePath  bool
+		expected int
+	}{
+		{
+			name:     "wildcard route",
+			path:     "/a/b/c",
+			expected: 3,
+		},
+		{
+			name:     "host route",
+			path:     "http://example.com/files/x/y/z",
+			expected: 2,
+		},
+		{
+			name:     "unmatched",
+			path:     "/x/y",
+			expected: -1,
+		},
+		{
+			name:     "unmatched with path depth",
+			path:     "/x/y",
+			usePath:  true,
+			expected: 2,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.path, tc.usePath, tc.expected))
+	}
+}
+
+func TestMiddleware_WithBandwidth(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = w.Write(make([]byte, 10_000))
+	})
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithBandwidth())
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	// 10,000 bytes in a little over 100ms.
+	bandwidth := attrs["http.bandwidth_bytes_per_sec"].Value.Float64()
+	assert.LessOrEqual(t, bandwidth, 100_000.0)
+	assert.Greater(t, bandwidth, 20_000.0)
+}
+
+func TestMiddleware_WithFormFields(t *testing.T) {
+	f := func(target, payload string, parse bool, expected map[string]string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			var body string
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if parse {
+					_ = r.ParseForm()
+				} else {
+					data, _ := io.ReadAll(r.Body)
+					body = string(data)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(payload))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithFormFields("page", "sort"))
+
+			mw.ServeHTTP(rr, r)
+
+			if !parse {
+				assert.Equal(t, payload, body)
+			}
+			assert.Len(t, th.records, 1)
+			got := make(map[string]string)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				if strings.HasPrefix(a.Key, "http.form.") {
+					got[a.Key] = a.Value.String()
+				}
+				return true
+			})
+			assert.Equal(t, expected, got)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		target   string
+		payload  string
+		parse    bool
+		expected map[string]string
+	}{
+		{
+			name:    "parsed by handler",
+			target:  "/?sort=name",
+			payload: "page=2&password=hunter2",
+			parse:   true,
+			expected: map[string]string{
+				"http.form.page": "2",
+				"http.form.sort": "name",
+			},
+		},
+		{
+			name:    "body not consumed",
+			target:  "/?page=3&password=hunter2",
+			payload: "sort=date",
+			expected: map[string]string{
+				"http.form.page": "3",
+			},
+		},
+		{
+			name:     "malformed form",
+			target:   "/",
+			payload:  "page=%zz",
+			parse:    true,
+			expected: map[string]string{},
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.target, tc.payload, tc.parse, tc.expected))
+	}
+}
+
+func TestMiddleware_WithLevelDiagnostics(t *testing.T) {
+	f := func(status int, canceled bool, opts []logging.Option, level slog.Level, reason string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(status)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if canceled {
+				ctx, cancel := context.WithCancel(r.Context())
+				cancel()
+				r = r.WithContext(ctx)
+			}
+
+			mw := logging.Wrap(mux, append(opts, logging.WithLogger(logger), logging.WithLevelDiagnostics())...)
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			assert.Equal(t, level, th.records[0].Level)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, reason, attrs["log.level_reason"].Value.String())
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		status   int
+		canceled bool
+		opts     []logging.Option
+		level    slog.Level
+		reason   string
+	}{
+		{
+			name:   "ok",
+			status: http.StatusOK,
+			level:  slog.LevelInfo,
+			reason: "status",
+		},
+		{
+			name:   "server error",
+			status: http.StatusServiceUnavailable,
+			level:  slog.LevelError,
+			reason: "status_5xx",
+		},
+		{
+			name:   "base level floor wins",
+			status: http.StatusOK,
+			opts:   []logging.Option{logging.WithBaseLevel(slog.LevelWarn, logging.BaseLevelFloor)},
+			level:  slog.LevelWarn,
+			reason: "base_level",
+		},
+		{
+			name:   "status beats base level floor",
+			status: http.StatusInternalServerError,
+			opts:   []logging.Option{logging.WithBaseLevel(slog.LevelWarn, logging.BaseLevelFloor)},
+			level:  slog.LevelError,
+			reason: "status_5xx",
+		},
+		{
+			name:     "canceled",
+			status:   http.StatusOK,
+			canceled: true,
+			opts:     []logging.Option{logging.WithCanceledLevel(slog.LevelDebug)},
+			level:    slog.LevelDebug,
+			reason:   "canceled",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.status, tc.canceled, tc.opts, tc.level, tc.reason))
+	}
+}
+
+func TestMiddleware_WithOutcome(t *testing.T) {
+	f := func(status int, ctxErr error, expected string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(status)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			switch ctxErr {
+			case context.Canceled:
+				ctx, cancel := context.WithCancel(r.Context())
+				cancel()
+				r = r.WithContext(ctx)
+			case context.DeadlineExceeded:
+				ctx, cancel := context.WithDeadline(r.Context(), time.Now())
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithOutcome())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, expected, attrs["http.outcome"].Value.String())
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		status   int
+		ctxErr   error
+		expected string
+	}{
+		{
+			name:     "ok",
+			status:   http.StatusOK,
+			expected: "success",
+		},
+		{
+			name:     "not found",
+			status:   http.StatusNotFound,
+			expected: "client_error",
+		},
+		{
+			name:     "service unavailable",
+			status:   http.StatusServiceUnavailable,
+			expected: "server_error",
+		},
+		{
+			name:     "canceled with ok status",
+			status:   http.StatusOK,
+			ctxErr:   context.Canceled,
+			expected: "canceled",
+		},
+		{
+			name:     "timed out",
+			status:   http.StatusServiceUnavailable,
+			ctxErr:   context.DeadlineExceeded,
+			expected: "timeout",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.status, tc.ctxErr, tc.expected))
+	}
+}
+
+func TestMiddleware_WithInstanceName(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+
+	public := logging.Wrap(mux, logging.WithLogger(logger), logging.WithInstanceName("public"))
+	admin := logging.Wrap(mux, logging.WithLogger(logger), logging.WithInstanceName("admin"))
+	unnamed := logging.Wrap(mux, logging.WithLogger(logger))
+
+	for _, mw := range []http.Handler{public, admin, unnamed} {
+		mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	assert.Len(t, th.records, 3)
+	var instances []string
+	for _, rec := range th.records {
+		instance := ""
+		rec.Attrs(func(a slog.Attr) bool {
+			if a.Key == "middleware.instance" {
+				instance = a.Value.String()
+			}
+			return true
+		})
+		instances = append(instances, instance)
+	}
+	assert.Equal(t, []string{"public", "admin", ""}, instances)
+}
+
 func ExampleWithPathFilter() {
 	// Create a new [http.Handler] with a healthcheck endpoint.
 	mux := http.NewServeMux()
@@ -311,7 +2459,7 @@ func ExampleWithPathFilter() {
 	handler.ServeHTTP(resp, req)
 
 	// Output:
-	// level=INFO msg="GET / [200]" http.status_code=200 http.path=/ http.method=GET http.route="GET /"
+	// level=INFO msg="GET / [200]" http.status_code=200 http.path=/ http.method=GET http.request_size=0 http.response_size=11 http.route="GET /"
 }
 
 func ExampleWithRouteFilter() {
@@ -346,5 +2494,5 @@ func ExampleWithRouteFilter() {
 	handler.ServeHTTP(resp, req)
 
 	// Output:
-	// level=INFO msg="POST /healthcheck [405]" http.status_code=405 http.path=/healthcheck http.method=POST
+	// level=INFO msg="POST /healthcheck [405]" http.status_code=405 http.path=/healthcheck http.method=POST http.request_size=0 http.response_size=19
 }
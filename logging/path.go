@@ -0,0 +1,63 @@
+package logging
+
+import "strings"
+
+// NormalizePath replaces the numeric and UUID segments of path with the
+// placeholders ":id" and ":uuid", so that e.g.
+// "/users/123/keys/0b5e2a58-7c5f-4d3e-9f0a-2b8c7d6e5f41" becomes
+// "/users/:id/keys/:uuid". It is meant for use with [WithPathTemplate].
+func NormalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		switch {
+		case isNumeric(seg):
+			segments[i] = ":id"
+		case isUUID(seg):
+			segments[i] = ":uuid"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := range len(s) {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isUUID reports whether s is a UUID in its canonical, hyphenated form.
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := range len(s) {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			c := s[i]
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// WithPathTemplate logs the result of calling fn with the request path as
+// http.path_template, to give dashboards a low-cardinality path when the
+// handler is not an [http.ServeMux] that provides a route. The raw path is
+// still logged as http.path. [NormalizePath] is a ready-made fn.
+func WithPathTemplate(fn func(path string) string) Option {
+	return func(mw *Middleware) {
+		mw.pathTemplate = fn
+	}
+}
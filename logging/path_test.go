@@ -0,0 +1,82 @@
+package logging_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+func TestNormalizePath(t *testing.T) {
+	f := func(path, expected string) func(*testing.T) {
+		return func(t *testing.T) {
+			assert.Equal(t, expected, logging.NormalizePath(path))
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{
+			name:     "numeric and UUID segments",
+			path:     "/users/123/keys/0b5e2a58-7c5f-4d3e-9f0a-2b8c7d6e5f41",
+			expected: "/users/:id/keys/:uuid",
+		},
+		{
+			name:     "uppercase UUID",
+			path:     "/orders/0B5E2A58-7C5F-4D3E-9F0A-2B8C7D6E5F41/items",
+			expected: "/orders/:uuid/items",
+		},
+		{
+			name:     "no dynamic segments",
+			path:     "/users/me/",
+			expected: "/users/me/",
+		},
+		{
+			name:     "mixed segment",
+			path:     "/v2/files/123abc",
+			expected: "/v2/files/123abc",
+		},
+		{
+			name:     "root",
+			path:     "/",
+			expected: "/",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.path, tc.expected))
+	}
+}
+
+func TestMiddleware_WithPathTemplate(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithPathTemplate(logging.NormalizePath))
+
+	mw.ServeHTTP(rr, r)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, "/users/42", attrs["http.path"].Value.String())
+	assert.Equal(t, "/users/:id", attrs["http.path_template"].Value.String())
+}
@@ -0,0 +1,61 @@
+package deadlineextractor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Options allows changing the attribute keys used by the extractor.
+type Options struct {
+	// Group defaults to empty. If it is set to a non-empty value, the
+	// attributes will be returned wrapped in a slog.Group with the
+	// given name.
+	Group string
+
+	// At defaults to "deadline.at". If a non-empty value is given, it
+	// is used instead.
+	At string
+
+	// Remaining defaults to "deadline.remaining". If a non-empty value
+	// is given, it is used instead.
+	Remaining string
+}
+
+// New returns a new [jsocol.io/middleware/logging.ContextExtractor]
+// that adds the deadline of the request context, if any, and the time
+// remaining until it at the moment the log is recorded.
+func New(opts *Options) func(context.Context) []slog.Attr {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	groupName := ""
+	if opts.Group != "" {
+		groupName = opts.Group
+	}
+
+	atName := "deadline.at"
+	if opts.At != "" {
+		atName = opts.At
+	}
+
+	remainingName := "deadline.remaining"
+	if opts.Remaining != "" {
+		remainingName = opts.Remaining
+	}
+
+	return func(ctx context.Context) []slog.Attr {
+		dl, ok := ctx.Deadline()
+		if !ok {
+			return nil
+		}
+
+		at := slog.String(atName, dl.Format(time.RFC3339Nano))
+		remaining := slog.Duration(remainingName, time.Until(dl))
+		if groupName != "" {
+			return []slog.Attr{slog.Group(groupName, at, remaining)}
+		}
+		return []slog.Attr{at, remaining}
+	}
+}
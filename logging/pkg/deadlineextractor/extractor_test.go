@@ -0,0 +1,46 @@
+package deadlineextractor_test
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"jsocol.io/middleware/logging/pkg/deadlineextractor"
+)
+
+func TestNew(t *testing.T) {
+	dl := time.Now().Add(5 * time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), dl)
+	defer cancel()
+
+	attrs := deadlineextractor.New(nil)(ctx)
+
+	assert.Len(t, attrs, 2)
+	assert.Equal(t, "deadline.at", attrs[0].Key)
+	assert.Equal(t, dl.Format(time.RFC3339Nano), attrs[0].Value.String())
+	assert.Equal(t, "deadline.remaining", attrs[1].Key)
+	assert.InDelta(t, 5*time.Second, attrs[1].Value.Duration(), float64(5*time.Millisecond))
+}
+
+func TestNew_WithGroup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	attrs := deadlineextractor.New(&deadlineextractor.Options{Group: "deadline", At: "at", Remaining: "remaining"})(ctx)
+
+	assert.Len(t, attrs, 1)
+	assert.Equal(t, "deadline", attrs[0].Key)
+	assert.Equal(t, slog.KindGroup, attrs[0].Value.Kind())
+	group := attrs[0].Value.Group()
+	assert.Equal(t, "at", group[0].Key)
+	assert.Equal(t, "remaining", group[1].Key)
+}
+
+func TestNew_NoDeadline(t *testing.T) {
+	attrs := deadlineextractor.New(nil)(context.Background())
+
+	assert.Empty(t, attrs)
+}
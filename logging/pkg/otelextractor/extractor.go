@@ -2,7 +2,10 @@ package otelextractor
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/binary"
 	"log/slog"
+	"net/http"
 
 	"go.opentelemetry.io/otel/trace"
 )
@@ -21,6 +24,46 @@ type Options struct {
 	// SpanID defaults to "span_id". If a non-empty value is given, it
 	// is used instead.
 	SpanID string
+
+	// Fallback defaults to false. If it is set to true and there is no
+	// valid SpanContext, a randomly generated ID is logged under the
+	// TraceID key instead, so that logs can still be correlated. Use
+	// [ContextWithFallbackID] or [WrapFallback] to keep the generated
+	// ID stable for the whole request.
+	Fallback bool
+}
+
+type fallbackKey struct{}
+
+// ContextWithFallbackID returns a copy of ctx carrying a newly generated
+// 128-bit ID, unless ctx already carries one. Extractors created with
+// [Options.Fallback] log this ID when there is no valid SpanContext.
+func ContextWithFallbackID(ctx context.Context) context.Context {
+	if _, ok := FallbackID(ctx); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, fallbackKey{}, newFallbackID())
+}
+
+// FallbackID returns the ID stored by [ContextWithFallbackID], if any.
+func FallbackID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(fallbackKey{}).(string)
+	return id, ok
+}
+
+// WrapFallback returns an [http.Handler] that stores a fallback ID in every
+// request's context before calling h. It must wrap the logging middleware,
+// which passes its own request context to extractors.
+func WrapFallback(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(ContextWithFallbackID(r.Context())))
+	})
+}
+
+func newFallbackID() string {
+	var id trace.TraceID
+	_, _ = rand.Read(id[:])
+	return id.String()
 }
 
 // New returns a new [jsocol.io/middleware/logging.ContextExtractor]
@@ -46,6 +89,8 @@ func New(opts *Options) func(context.Context) []slog.Attr {
 		spanIDName = opts.SpanID
 	}
 
+	fallback := opts.Fallback
+
 	return func(ctx context.Context) []slog.Attr {
 		sc := trace.SpanContextFromContext(ctx)
 		var attrs []slog.Attr
@@ -62,8 +107,36 @@ func New(opts *Options) func(context.Context) []slog.Attr {
 			} else {
 				attrs = append(attrs, traceID, spanID)
 			}
+		} else if fallback {
+			id, ok := FallbackID(ctx)
+			if !ok {
+				id = newFallbackID()
+			}
+			traceID := slog.String(traceIDName, id)
+			if groupName != "" {
+				attrs = append(attrs, slog.Group(groupName, traceID))
+			} else {
+				attrs = append(attrs, traceID)
+			}
 		}
 
 		return attrs
 	}
 }
+
+// SamplingKey returns the low 64 bits of the trace ID of the active span in
+// the request's context, for use with
+// [jsocol.io/middleware/logging.WithSamplingKey], so that the access log is
+// sampled consistently with the trace. The span must be started before the
+// logging middleware runs. Requests without a valid SpanContext get a random
+// key.
+func SamplingKey(r *http.Request) uint64 {
+	sc := trace.SpanContextFromContext(r.Context())
+	if !sc.IsValid() {
+		var b [8]byte
+		_, _ = rand.Read(b[:])
+		return binary.BigEndian.Uint64(b[:])
+	}
+	id := sc.TraceID()
+	return binary.BigEndian.Uint64(id[8:])
+}
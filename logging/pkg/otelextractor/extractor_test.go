@@ -0,0 +1,69 @@
+package otelextractor_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"jsocol.io/middleware/logging/pkg/otelextractor"
+)
+
+func TestNew_SpanContext(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01, 0x02, 0x03},
+		SpanID:  trace.SpanID{0x04, 0x05, 0x06},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	attrs := otelextractor.New(&otelextractor.Options{Fallback: true})(ctx)
+
+	assert.Len(t, attrs, 2)
+	assert.Equal(t, "trace_id", attrs[0].Key)
+	assert.Equal(t, sc.TraceID().String(), attrs[0].Value.String())
+	assert.Equal(t, "span_id", attrs[1].Key)
+	assert.Equal(t, sc.SpanID().String(), attrs[1].Value.String())
+}
+
+func TestNew_NoSpanContext(t *testing.T) {
+	attrs := otelextractor.New(nil)(context.Background())
+
+	assert.Empty(t, attrs)
+}
+
+func TestNew_Fallback(t *testing.T) {
+	var ids []string
+	extractor := otelextractor.New(&otelextractor.Options{Fallback: true})
+	h := otelextractor.WrapFallback(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		for range 2 {
+			attrs := extractor(r.Context())
+			assert.Len(t, attrs, 1)
+			assert.Equal(t, "trace_id", attrs[0].Key)
+			ids = append(ids, attrs[0].Value.String())
+		}
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Len(t, ids, 4)
+	assert.Len(t, ids[0], 32)
+	assert.Equal(t, ids[0], ids[1], "stable within a request")
+	assert.NotEqual(t, ids[0], ids[2], "unique across requests")
+	assert.Equal(t, ids[2], ids[3], "stable within a request")
+}
+
+func TestSamplingKey(t *testing.T) {
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01, 0x02, 0x03, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x12, 0x34},
+		SpanID:  trace.SpanID{0x04, 0x05, 0x06},
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(trace.ContextWithSpanContext(r.Context(), sc))
+
+	assert.Equal(t, uint64(0x1234), otelextractor.SamplingKey(r))
+	assert.Equal(t, uint64(0x1234), otelextractor.SamplingKey(r))
+}
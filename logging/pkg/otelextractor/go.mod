@@ -2,6 +2,14 @@ module jsocol.io/middleware/logging/pkg/otelextractor
 
 go 1.24.5
 
-require go.opentelemetry.io/otel/trace v1.37.0
+require (
+	github.com/stretchr/testify v1.10.0
+	go.opentelemetry.io/otel/trace v1.37.0
+)
 
-require go.opentelemetry.io/otel v1.37.0 // indirect
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	go.opentelemetry.io/otel v1.37.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
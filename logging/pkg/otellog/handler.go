@@ -0,0 +1,176 @@
+// Package otellog provides a [slog.Handler] that emits the records
+// written by jsocol.io/middleware/logging as OpenTelemetry log records.
+package otellog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/log"
+)
+
+// Handler is a [slog.Handler] that converts each record into an
+// OpenTelemetry log record and emits it with a [log.Logger]. The record
+// message becomes the body, the level becomes the severity, and the
+// attributes, including the HTTP semantic convention keys logged by
+// logging.WithOTelSemconv, become log attributes. Groups become nested
+// maps.
+type Handler struct {
+	logger log.Logger
+	// frames holds the attributes added with WithAttrs for each open
+	// group. The first frame is the root and has no name.
+	frames []frame
+}
+
+type frame struct {
+	name  string
+	attrs []log.KeyValue
+}
+
+var _ slog.Handler = &Handler{}
+
+// NewHandler returns a Handler that emits records with logger, usually
+// obtained from a [log.LoggerProvider]:
+//
+//	logger := provider.Logger("jsocol.io/middleware/logging")
+//	logging.WithLogger(slog.New(otellog.NewHandler(logger)))
+func NewHandler(logger log.Logger) *Handler {
+	return &Handler{
+		logger: logger,
+		frames: []frame{{}},
+	}
+}
+
+// Enabled reports whether the underlying logger emits records at level.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger.Enabled(ctx, log.EnabledParameters{Severity: severity(level)})
+}
+
+// Handle converts r and emits it. The context is passed along so that the
+// SDK can correlate the record with the active span.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	var rec log.Record
+	rec.SetTimestamp(r.Time)
+	rec.SetSeverity(severity(r.Level))
+	rec.SetSeverityText(r.Level.String())
+	rec.SetBody(log.StringValue(r.Message))
+
+	last := h.frames[len(h.frames)-1]
+	kvs := slices.Clone(last.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		kvs = appendAttr(kvs, a)
+		return true
+	})
+	for i := len(h.frames) - 1; i > 0; i-- {
+		parent := slices.Clone(h.frames[i-1].attrs)
+		if len(kvs) > 0 {
+			parent = append(parent, log.Map(h.frames[i].name, kvs...))
+		}
+		kvs = parent
+	}
+	rec.AddAttributes(kvs...)
+
+	h.logger.Emit(ctx, rec)
+	return nil
+}
+
+// WithAttrs returns a Handler that adds attrs to every record, inside the
+// currently open group, if any.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	frames := slices.Clone(h.frames)
+	last := &frames[len(frames)-1]
+	last.attrs = slices.Clone(last.attrs)
+	for _, a := range attrs {
+		last.attrs = appendAttr(last.attrs, a)
+	}
+	return &Handler{logger: h.logger, frames: frames}
+}
+
+// WithGroup returns a Handler that nests all following attributes in a map
+// attribute with the given name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &Handler{
+		logger: h.logger,
+		frames: append(slices.Clone(h.frames), frame{name: name}),
+	}
+}
+
+// severity maps slog levels onto OpenTelemetry severities, keeping the
+// offsets between levels: slog.LevelInfo is log.SeverityInfo, and
+// slog.LevelInfo+1 is log.SeverityInfo2.
+func severity(level slog.Level) log.Severity {
+	s := int(level) + int(log.SeverityInfo)
+	return log.Severity(min(max(s, int(log.SeverityTrace1)), int(log.SeverityFatal4)))
+}
+
+// appendAttr converts a and appends it to kvs, following the slog.Handler
+// rules: empty attributes and empty groups are dropped, and groups with an
+// empty key are inlined.
+func appendAttr(kvs []log.KeyValue, a slog.Attr) []log.KeyValue {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(kvs, log.KeyValue{Key: a.Key, Value: convertValue(a.Value)})
+	}
+	group := a.Value.Group()
+	if len(group) == 0 {
+		return kvs
+	}
+	if a.Key == "" {
+		for _, ga := range group {
+			kvs = appendAttr(kvs, ga)
+		}
+		return kvs
+	}
+	var members []log.KeyValue
+	for _, ga := range group {
+		members = appendAttr(members, ga)
+	}
+	return append(kvs, log.Map(a.Key, members...))
+}
+
+// convertValue converts a resolved slog.Value. Durations are reported as
+// floating point seconds, the unit the HTTP semantic conventions use for
+// request duration.
+func convertValue(v slog.Value) log.Value {
+	switch v.Kind() {
+	case slog.KindBool:
+		return log.BoolValue(v.Bool())
+	case slog.KindInt64:
+		return log.Int64Value(v.Int64())
+	case slog.KindUint64:
+		u := v.Uint64()
+		if u > math.MaxInt64 {
+			return log.StringValue(fmt.Sprint(u))
+		}
+		return log.Int64Value(int64(u))
+	case slog.KindFloat64:
+		return log.Float64Value(v.Float64())
+	case slog.KindString:
+		return log.StringValue(v.String())
+	case slog.KindDuration:
+		return log.Float64Value(v.Duration().Seconds())
+	case slog.KindTime:
+		return log.StringValue(v.Time().Format(time.RFC3339Nano))
+	}
+
+	switch x := v.Any().(type) {
+	case []byte:
+		return log.BytesValue(x)
+	case error:
+		return log.StringValue(x.Error())
+	}
+	return log.StringValue(fmt.Sprint(v.Any()))
+}
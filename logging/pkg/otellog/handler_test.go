@@ -0,0 +1,153 @@
+package otellog_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+
+	"jsocol.io/middleware/logging/pkg/otellog"
+)
+
+// memoryExporter keeps exported records in memory.
+type memoryExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *memoryExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, r := range records {
+		e.records = append(e.records, r.Clone())
+	}
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(context.Context) error   { return nil }
+func (e *memoryExporter) ForceFlush(context.Context) error { return nil }
+
+func newLogger(t *testing.T) (*slog.Logger, *memoryExporter) {
+	t.Helper()
+	exporter := &memoryExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return slog.New(otellog.NewHandler(provider.Logger("test"))), exporter
+}
+
+func attributes(r sdklog.Record) map[string]log.Value {
+	attrs := make(map[string]log.Value)
+	r.WalkAttributes(func(kv log.KeyValue) bool {
+		attrs[kv.Key] = kv.Value
+		return true
+	})
+	return attrs
+}
+
+func TestHandler_HTTPAttributes(t *testing.T) {
+	logger, exporter := newLogger(t)
+
+	// The attributes logged by logging.WithOTelSemconv.
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "GET /foo/1234 [404]",
+		slog.Int("http.response.status_code", http.StatusNotFound),
+		slog.String("url.path", "/foo/1234"),
+		slog.String("http.request.method", http.MethodGet),
+		slog.Duration("duration", 1500*time.Millisecond),
+		slog.Int64("http.response.body.size", 19),
+		slog.String("http.route", "GET /foo/{id}"),
+	)
+
+	assert.Len(t, exporter.records, 1)
+	rec := exporter.records[0]
+	assert.Equal(t, "GET /foo/1234 [404]", rec.Body().AsString())
+	assert.Equal(t, log.SeverityInfo, rec.Severity())
+	assert.Equal(t, "INFO", rec.SeverityText())
+
+	attrs := attributes(rec)
+	assert.Equal(t, int64(http.StatusNotFound), attrs["http.response.status_code"].AsInt64())
+	assert.Equal(t, http.MethodGet, attrs["http.request.method"].AsString())
+	assert.Equal(t, "/foo/1234", attrs["url.path"].AsString())
+	assert.Equal(t, "GET /foo/{id}", attrs["http.route"].AsString())
+	assert.Equal(t, int64(19), attrs["http.response.body.size"].AsInt64())
+	assert.Equal(t, log.KindFloat64, attrs["duration"].Kind())
+	assert.Equal(t, 1.5, attrs["duration"].AsFloat64())
+}
+
+func TestHandler_Severity(t *testing.T) {
+	f := func(level slog.Level, expected log.Severity) func(*testing.T) {
+		return func(t *testing.T) {
+			logger, exporter := newLogger(t)
+			logger.Log(context.Background(), level, "message")
+
+			assert.Len(t, exporter.records, 1)
+			assert.Equal(t, expected, exporter.records[0].Severity())
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		level    slog.Level
+		expected log.Severity
+	}{
+		{
+			name:     "debug",
+			level:    slog.LevelDebug,
+			expected: log.SeverityDebug,
+		},
+		{
+			name:     "info",
+			level:    slog.LevelInfo,
+			expected: log.SeverityInfo,
+		},
+		{
+			name:     "warn",
+			level:    slog.LevelWarn,
+			expected: log.SeverityWarn,
+		},
+		{
+			name:     "error",
+			level:    slog.LevelError,
+			expected: log.SeverityError,
+		},
+		{
+			name:     "clamped",
+			level:    slog.LevelError + 100,
+			expected: log.SeverityFatal4,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.level, tc.expected))
+	}
+}
+
+func TestHandler_Groups(t *testing.T) {
+	logger, exporter := newLogger(t)
+
+	logger.With("service", "api").WithGroup("http").With("scheme", "https").Info("message",
+		slog.Int("status", 200),
+		slog.Group("", slog.String("inlined", "yes")),
+		slog.Group("empty"),
+	)
+
+	assert.Len(t, exporter.records, 1)
+	attrs := attributes(exporter.records[0])
+	assert.Len(t, attrs, 2)
+	assert.Equal(t, "api", attrs["service"].AsString())
+
+	group := make(map[string]log.Value)
+	for _, kv := range attrs["http"].AsMap() {
+		group[kv.Key] = kv.Value
+	}
+	assert.Len(t, group, 3)
+	assert.Equal(t, "https", group["scheme"].AsString())
+	assert.Equal(t, int64(200), group["status"].AsInt64())
+	assert.Equal(t, "yes", group["inlined"].AsString())
+}
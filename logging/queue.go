@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseRequestStart parses a request start timestamp as set by edge proxies,
+// with an optional "t=" prefix. Values with a fraction are in seconds, as
+// sent by nginx, and whole numbers are taken as seconds, milliseconds or
+// microseconds depending on their magnitude.
+func parseRequestStart(value string) (time.Time, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "t=")
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	if strings.Contains(value, ".") {
+		secs, err := strconv.ParseFloat(value, 64)
+		if err != nil || secs <= 0 {
+			return time.Time{}, false
+		}
+		return time.UnixMicro(int64(secs * 1e6)), true
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case n >= 1e15:
+		return time.UnixMicro(n), true
+	case n >= 1e12:
+		return time.UnixMilli(n), true
+	default:
+		return time.Unix(n, 0), true
+	}
+}
+
+// queueDelay returns how long before start the request was received
+// according to the value of the queue delay header. Timestamps in the future
+// are treated as no delay.
+func queueDelay(value string, start time.Time) (time.Duration, bool) {
+	received, ok := parseRequestStart(value)
+	if !ok {
+		return 0, false
+	}
+	return max(start.Sub(received), 0), true
+}
+
+// WithQueueDelayHeader logs the time between the arrival timestamp that an
+// edge proxy stamped in the named request header, e.g. "X-Request-Start", and
+// the start of the request in this server as http.queue_delay. Timestamps can
+// be in seconds, milliseconds or microseconds, optionally prefixed with "t=".
+// Malformed values are ignored, and timestamps in the future, e.g. from
+// clock skew, count as no delay.
+func WithQueueDelayHeader(name string) Option {
+	return func(mw *Middleware) {
+		mw.queueHeader = name
+	}
+}
+
+type entryKey struct{}
+
+func contextWithEntry(ctx context.Context, entry time.Time) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+func entryFromContext(ctx context.Context) (time.Time, bool) {
+	entry, ok := ctx.Value(entryKey{}).(time.Time)
+	return entry, ok
+}
+
+// MarkEntry wraps h so that every request's context carries the time it
+// entered h. Use it as the outermost handler so that [WithMiddlewareDelay]
+// can measure the time spent in the middleware in front of the logger.
+// Requests that already carry an entry time keep it.
+func MarkEntry(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := entryFromContext(r.Context()); !ok {
+			r = r.WithContext(contextWithEntry(r.Context(), time.Now()))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// WithMiddlewareDelay logs the time between the entry time stamped in the
+// request context and the start of the request in this Middleware as
+// http.middleware_delay. This is the time spent in preceding middleware.
+// The entry time is stamped by [MarkEntry] or, if there is none, by the
+// outermost Middleware with this option, which then logs no delay itself.
+func WithMiddlewareDelay() Option {
+	return func(mw *Middleware) {
+		mw.entryDelay = true
+	}
+}
@@ -0,0 +1,145 @@
+package logging_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+func TestMiddleware_WithQueueDelayHeader(t *testing.T) {
+	f := func(value string, expected time.Duration, logged bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Request-Start", value)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithQueueDelayHeader("X-Request-Start"))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if logged {
+				// Timestamps are truncated to the header's precision.
+				delay := attrs["http.queue_delay"].Value.Duration()
+				assert.GreaterOrEqual(t, delay, expected)
+				assert.Less(t, delay, expected+time.Second)
+				if expected == 0 {
+					assert.Zero(t, delay)
+				}
+			} else {
+				assert.NotContains(t, attrs, "http.queue_delay")
+			}
+		}
+	}
+
+	past := time.Now().Add(-300 * time.Millisecond)
+
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		logged   bool
+	}{
+		{
+			name:     "milliseconds",
+			value:    strconv.FormatInt(past.UnixMilli(), 10),
+			expected: 299 * time.Millisecond,
+			logged:   true,
+		},
+		{
+			name:     "microseconds",
+			value:    strconv.FormatInt(past.UnixMicro(), 10),
+			expected: 299 * time.Millisecond,
+			logged:   true,
+		},
+		{
+			name:     "seconds with prefix",
+			value:    "t=" + strconv.FormatFloat(float64(past.UnixMicro())/1e6, 'f', 3, 64),
+			expected: 299 * time.Millisecond,
+			logged:   true,
+		},
+		{
+			name:   "future timestamp is clamped",
+			value:  strconv.FormatInt(time.Now().Add(time.Minute).UnixMilli(), 10),
+			logged: true,
+		},
+		{
+			name:  "malformed",
+			value: "yesterday",
+		},
+		{
+			name: "missing",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.value, tc.expected, tc.logged))
+	}
+}
+
+func TestMiddleware_WithMiddlewareDelay(t *testing.T) {
+	attrsOf := func(rec slog.Record) map[string]slog.Attr {
+		attrs := make(map[string]slog.Attr)
+		rec.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a
+			return true
+		})
+		return attrs
+	}
+
+	delay := 10 * time.Millisecond
+	slow := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			h.ServeHTTP(w, r)
+		})
+	}
+
+	t.Run("nested", func(t *testing.T) {
+		outerTH := &testHandler{}
+		innerTH := &testHandler{}
+		mux := http.NewServeMux()
+
+		inner := logging.Wrap(mux, logging.WithLogger(slog.New(innerTH)), logging.WithMiddlewareDelay())
+		outer := logging.Wrap(slow(inner), logging.WithLogger(slog.New(outerTH)), logging.WithMiddlewareDelay())
+
+		outer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Len(t, outerTH.records, 1)
+		assert.NotContains(t, attrsOf(outerTH.records[0]), "http.middleware_delay")
+
+		assert.Len(t, innerTH.records, 1)
+		got := attrsOf(innerTH.records[0])["http.middleware_delay"].Value.Duration()
+		assert.GreaterOrEqual(t, got, delay)
+	})
+
+	t.Run("marked entry", func(t *testing.T) {
+		th := &testHandler{}
+		mux := http.NewServeMux()
+
+		mw := logging.MarkEntry(slow(logging.Wrap(mux, logging.WithLogger(slog.New(th)), logging.WithMiddlewareDelay())))
+
+		mw.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Len(t, th.records, 1)
+		got := attrsOf(th.records[0])["http.middleware_delay"].Value.Duration()
+		assert.GreaterOrEqual(t, got, delay)
+	})
+}
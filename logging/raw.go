@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RawFields are the fields of a request available to a [Formatter].
+type RawFields struct {
+	Time     time.Time
+	Method   string
+	Path     string
+	Route    string
+	Status   int
+	Duration time.Duration
+}
+
+// A Formatter renders a request for [WithRawOutput] by appending it, with a
+// trailing newline, to buf and returning the extended buffer, in the manner
+// of [strconv.AppendInt]. buf is reused between requests, so a Formatter
+// must not retain it.
+type Formatter func(buf []byte, f RawFields) []byte
+
+// CommonFormat is a compact [Formatter] that renders a request as the time in
+// UTC, the method, quoted path, status and duration in milliseconds, followed
+// by the quoted route if there is one, e.g.:
+//
+//	2025-01-02T15:04:05.000Z GET "/users/1234" 200 1.234ms "GET /users/{id}"
+func CommonFormat(buf []byte, f RawFields) []byte {
+	buf = f.Time.UTC().AppendFormat(buf, "2006-01-02T15:04:05.000Z07:00")
+	buf = append(buf, ' ')
+	buf = append(buf, f.Method...)
+	buf = append(buf, ' ')
+	buf = strconv.AppendQuote(buf, f.Path)
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, int64(f.Status), 10)
+	buf = append(buf, ' ')
+	buf = strconv.AppendFloat(buf, float64(f.Duration)/float64(time.Millisecond), 'f', 3, 64)
+	buf = append(buf, "ms"...)
+	if f.Route != "" {
+		buf = append(buf, ' ')
+		buf = strconv.AppendQuote(buf, f.Route)
+	}
+	return append(buf, '\n')
+}
+
+// maxPooledBuffer is the largest buffer returned to rawBuffers, so that an
+// occasional huge line does not stay in memory.
+const maxPooledBuffer = 64 << 10
+
+var rawBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, 0, 256)
+		return &buf
+	},
+}
+
+// serveRaw is [Middleware.ServeHTTP] for [WithRawOutput]. It bypasses the
+// logger and writes each request as a single pre-rendered line.
+func (m *Middleware) serveRaw(w http.ResponseWriter, r *http.Request) {
+	ww := &wrappedWriter{
+		ResponseWriter: w,
+	}
+	start := time.Now()
+
+	state, ctx := newRequestState(r.Context())
+	r = r.WithContext(ctx)
+
+	defer func() {
+		duration := time.Since(start)
+
+		if m.filterPath(r.URL.Path) || (state.route != "" && m.filterRoute(state.route)) {
+			return
+		}
+
+		bp := rawBuffers.Get().(*[]byte)
+		buf := m.rawFormat((*bp)[:0], RawFields{
+			Time:     start,
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Route:    state.route,
+			Status:   ww.status,
+			Duration: duration,
+		})
+
+		m.rawMu.Lock()
+		_, _ = m.rawOut.Write(buf)
+		m.rawMu.Unlock()
+
+		if cap(buf) <= maxPooledBuffer {
+			*bp = buf
+			rawBuffers.Put(bp)
+		}
+	}()
+
+	if h := m.router(); h != nil {
+		_, state.route = h.Handler(r)
+	}
+
+	m.target.ServeHTTP(ww.writer(), r)
+}
+
+// WithRawOutput bypasses the logger for edge proxies and other services where
+// even the overhead of [slog] matters, and writes every request to w as a
+// single line rendered by format, or by [CommonFormat] if format is nil.
+// Writes to w are serialized. Only the path and route filters apply; other
+// options that add attributes or change the log level have no effect.
+func WithRawOutput(w io.Writer, format Formatter) Option {
+	if format == nil {
+		format = CommonFormat
+	}
+	return func(mw *Middleware) {
+		mw.rawOut = w
+		mw.rawFormat = format
+	}
+}
@@ -0,0 +1,92 @@
+package logging_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+func TestCommonFormat(t *testing.T) {
+	f := func(fields logging.RawFields, expected string) func(*testing.T) {
+		return func(t *testing.T) {
+			buf := logging.CommonFormat([]byte("prefix "), fields)
+			assert.Equal(t, "prefix "+expected, string(buf))
+		}
+	}
+
+	at := time.Date(2025, 1, 2, 15, 4, 5, 6e6, time.FixedZone("EST", -5*60*60))
+
+	testCases := []struct {
+		name     string
+		fields   logging.RawFields
+		expected string
+	}{
+		{
+			name: "with route",
+			fields: logging.RawFields{
+				Time:     at,
+				Method:   http.MethodGet,
+				Path:     "/users/1234",
+				Route:    "GET /users/{id}",
+				Status:   http.StatusOK,
+				Duration: 1234 * time.Microsecond,
+			},
+			expected: `2025-01-02T20:04:05.006Z GET "/users/1234" 200 1.234ms "GET /users/{id}"` + "\n",
+		},
+		{
+			name: "without route",
+			fields: logging.RawFields{
+				Time:     at,
+				Method:   http.MethodPost,
+				Path:     "/with space",
+				Status:   http.StatusNotFound,
+				Duration: 2 * time.Second,
+			},
+			expected: `2025-01-02T20:04:05.006Z POST "/with space" 404 2000.000ms` + "\n",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.fields, tc.expected))
+	}
+}
+
+func TestMiddleware_WithRawOutput(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /healthcheck", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var out bytes.Buffer
+	var got []logging.RawFields
+	format := func(buf []byte, f logging.RawFields) []byte {
+		got = append(got, f)
+		return logging.CommonFormat(buf, f)
+	}
+
+	mw := logging.Wrap(mux, logging.WithRawOutput(&out, format), logging.WithPathFilter("/healthcheck"))
+
+	for _, path := range []string{"/users/1234", "/healthcheck"} {
+		rr := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		mw.ServeHTTP(rr, r)
+	}
+
+	assert.Len(t, got, 1)
+	assert.Equal(t, http.MethodGet, got[0].Method)
+	assert.Equal(t, "/users/1234", got[0].Path)
+	assert.Equal(t, "GET /users/{id}", got[0].Route)
+	assert.Equal(t, http.StatusNoContent, got[0].Status)
+	assert.NotZero(t, got[0].Duration)
+	assert.Equal(t, string(logging.CommonFormat(nil, got[0])), out.String())
+}
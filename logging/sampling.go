@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// sample decides whether to keep the log for a request. Server errors and, if
+// configured, slow requests are always kept.
+func (m *Middleware) sample(r *http.Request, status int, duration time.Duration) bool {
+	if m.sampleRate >= 1 || status >= 500 {
+		return true
+	}
+	if m.sampleSlow > 0 && duration >= m.sampleSlow {
+		return true
+	}
+	if m.sampleKey != nil {
+		if key, ok := m.sampleKey(r); ok {
+			// This matches the OpenTelemetry TraceIDRatioBased sampler
+			// when the key is the low 64 bits of the trace ID.
+			return key>>1 < uint64(m.sampleRate*(1<<63))
+		}
+	}
+	return rand.Float64() < m.sampleRate
+}
+
+// TraceParentKey returns the low 64 bits of the trace ID in the request's W3C
+// traceparent header, the default key for [WithSamplingKey], and whether the
+// header was valid.
+func TraceParentKey(r *http.Request) (uint64, bool) {
+	// e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+	tp := r.Header.Get("Traceparent")
+	if len(tp) < 55 || tp[2] != '-' || tp[35] != '-' || tp[52] != '-' || tp[:2] == "ff" {
+		return 0, false
+	}
+	high, err := strconv.ParseUint(tp[3:19], 16, 64)
+	if err != nil {
+		return 0, false
+	}
+	low, err := strconv.ParseUint(tp[19:35], 16, 64)
+	if err != nil || high == 0 && low == 0 {
+		return 0, false
+	}
+	return low, true
+}
+
+// WithSampleRate only logs a random fraction of requests, between 0 (none)
+// and 1 (all, the default). Requests with a status of 500 or higher are always
+// logged.
+func WithSampleRate(rate float64) Option {
+	return func(mw *Middleware) {
+		mw.sampleRate = rate
+	}
+}
+
+// WithSampleKeepSlow always logs requests that take at least d, regardless of
+// the rate set by [WithSampleRate].
+func WithSampleKeepSlow(d time.Duration) Option {
+	return func(mw *Middleware) {
+		mw.sampleSlow = d
+	}
+}
+
+// WithSamplingKey makes the decision of [WithSampleRate] deterministic for
+// requests with the same key, so that a request kept here is also kept by
+// peers that sample on the same key, e.g. one derived from the trace ID. Keys
+// should be uniformly distributed. A request is kept if its key, shifted
+// right by one bit, is less than the rate times 2^63, as in the OpenTelemetry
+// TraceIDRatioBased sampler.
+//
+// If key is nil, [TraceParentKey] is used, and requests without a valid
+// traceparent header are sampled randomly. See
+// [jsocol.io/middleware/logging/pkg/otelextractor.SamplingKey] to key on the
+// trace ID of an active span instead.
+func WithSamplingKey(key func(r *http.Request) uint64) Option {
+	return func(mw *Middleware) {
+		if key == nil {
+			mw.sampleKey = TraceParentKey
+			return
+		}
+		mw.sampleKey = func(r *http.Request) (uint64, bool) {
+			return key(r), true
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package logging_test
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+func TestMiddleware_WithSampleRate(t *testing.T) {
+	f := func(status int, delay time.Duration, shouldLog bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(delay)
+				w.WriteHeader(status)
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux,
+				logging.WithLogger(logger),
+				logging.WithSampleRate(0),
+				logging.WithSampleKeepSlow(20*time.Millisecond),
+			)
+
+			mw.ServeHTTP(rr, r)
+
+			if shouldLog {
+				assert.NotEmpty(t, th.records)
+			} else {
+				assert.Empty(t, th.records)
+			}
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		status    int
+		delay     time.Duration
+		shouldLog bool
+	}{
+		{
+			name:   "fast success is sampled out",
+			status: http.StatusOK,
+		},
+		{
+			name:      "server error is kept",
+			status:    http.StatusInternalServerError,
+			shouldLog: true,
+		},
+		{
+			name:      "slow success is kept",
+			status:    http.StatusOK,
+			delay:     30 * time.Millisecond,
+			shouldLog: true,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.status, tc.delay, tc.shouldLog))
+	}
+}
+
+func TestMiddleware_WithSamplingKey(t *testing.T) {
+	f := func(key func(*http.Request) uint64, traceparent string, kept int) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			mw := logging.Wrap(mux,
+				logging.WithLogger(logger),
+				logging.WithSampleRate(0.5),
+				logging.WithSamplingKey(key),
+			)
+
+			for range 20 {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				if traceparent != "" {
+					r.Header.Set("Traceparent", traceparent)
+				}
+				mw.ServeHTTP(httptest.NewRecorder(), r)
+			}
+
+			assert.Len(t, th.records, kept)
+		}
+	}
+
+	constant := func(key uint64) func(*http.Request) uint64 {
+		return func(*http.Request) uint64 { return key }
+	}
+
+	testCases := []struct {
+		name        string
+		key         func(*http.Request) uint64
+		traceparent string
+		kept        int
+	}{
+		{
+			name: "key below the rate is always kept",
+			key:  constant(1 << 62),
+			kept: 20,
+		},
+		{
+			name: "key above the rate is always dropped",
+			key:  constant(3 << 62),
+		},
+		{
+			name:        "traceparent below the rate is always kept",
+			traceparent: "00-4bf92f3577b34da623ce929d0e0e4736-00f067aa0ba902b7-01",
+			kept:        20,
+		},
+		{
+			name:        "traceparent above the rate is always dropped",
+			traceparent: "00-4bf92f3577b34da6e3ce929d0e0e4736-00f067aa0ba902b7-01",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.key, tc.traceparent, tc.kept))
+	}
+}
+
+func TestTraceParentKey(t *testing.T) {
+	f := func(traceparent string, expected uint64, valid bool) func(*testing.T) {
+		return func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if traceparent != "" {
+				r.Header.Set("Traceparent", traceparent)
+			}
+
+			key, ok := logging.TraceParentKey(r)
+
+			assert.Equal(t, valid, ok)
+			assert.Equal(t, expected, key)
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		traceparent string
+		expected    uint64
+		valid       bool
+	}{
+		{
+			name:        "valid",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expected:    0xa3ce929d0e0e4736,
+			valid:       true,
+		},
+		{
+			name: "missing",
+		},
+		{
+			name:        "malformed",
+			traceparent: "00-not-a-trace",
+		},
+		{
+			name:        "zero trace ID",
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.traceparent, tc.expected, tc.valid))
+	}
+}
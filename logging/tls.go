@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net/http"
+)
+
+// tlsAttrs describes the TLS connection of r.
+func (m *Middleware) tlsAttrs(r *http.Request) []slog.Attr {
+	if r.TLS == nil {
+		return nil
+	}
+
+	var attrs []slog.Attr
+	if m.tlsInfo {
+		attrs = append(attrs, slog.String("tls.version", tls.VersionName(r.TLS.Version)))
+		if proto := r.TLS.NegotiatedProtocol; proto != "" {
+			attrs = append(attrs, slog.String("tls.alpn", proto))
+		}
+	}
+	if m.clientCert && len(r.TLS.PeerCertificates) > 0 {
+		leaf := r.TLS.PeerCertificates[0]
+		attrs = append(attrs,
+			slog.String("tls.client_subject", leaf.Subject.String()),
+			slog.String("tls.client_issuer", leaf.Issuer.String()),
+			slog.String("tls.client_serial", leaf.SerialNumber.String()),
+			slog.Bool("tls.client_verified", len(r.TLS.VerifiedChains) > 0),
+		)
+	}
+	return attrs
+}
+
+// WithClientCert logs the identity of the client certificate presented in
+// mutual TLS: the subject, issuer and serial number of the leaf certificate as
+// tls.client_subject, tls.client_issuer and tls.client_serial, and whether
+// it was verified as tls.client_verified, which can be false when the server
+// only verifies certificates if given. Requests without a client certificate
+// do not get the attributes.
+func WithClientCert() Option {
+	return func(mw *Middleware) {
+		mw.clientCert = true
+	}
+}
+
+// WithTLSInfo logs the TLS version of the connection, e.g. "TLS 1.3", as
+// tls.version and, when one was negotiated with ALPN, the application
+// protocol, e.g. "h2", as tls.alpn, to help debug protocol negotiation.
+// Requests over plain connections do not get the attributes.
+func WithTLSInfo() Option {
+	return func(mw *Middleware) {
+		mw.tlsInfo = true
+	}
+}
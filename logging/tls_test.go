@@ -0,0 +1,172 @@
+package logging_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+func newTestCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1234),
+		Subject:      pkix.Name{CommonName: "client.example.com", Organization: []string{"Example"}},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert
+}
+
+func TestMiddleware_WithClientCert(t *testing.T) {
+	cert := newTestCert(t)
+
+	f := func(state *tls.ConnectionState, logged, verified bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.TLS = state
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithClientCert())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if logged {
+				assert.Equal(t, "CN=client.example.com,O=Example", attrs["tls.client_subject"].Value.String())
+				assert.Equal(t, "CN=client.example.com,O=Example", attrs["tls.client_issuer"].Value.String())
+				assert.Equal(t, "1234", attrs["tls.client_serial"].Value.String())
+				assert.Equal(t, verified, attrs["tls.client_verified"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "tls.client_subject")
+				assert.NotContains(t, attrs, "tls.client_verified")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		state    *tls.ConnectionState
+		logged   bool
+		verified bool
+	}{
+		{
+			name:   "with unverified peer certificate",
+			state:  &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+			logged: true,
+		},
+		{
+			name: "with verified peer certificate",
+			state: &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{cert},
+				VerifiedChains:   [][]*x509.Certificate{{cert}},
+			},
+			logged:   true,
+			verified: true,
+		},
+		{
+			name:  "tls without peer certificate",
+			state: &tls.ConnectionState{},
+		},
+		{
+			name: "without tls",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.state, tc.logged, tc.verified))
+	}
+}
+
+func TestMiddleware_WithTLSInfo(t *testing.T) {
+	f := func(state *tls.ConnectionState, version, alpn string) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.TLS = state
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithTLSInfo())
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if version != "" {
+				assert.Equal(t, version, attrs["tls.version"].Value.String())
+			} else {
+				assert.NotContains(t, attrs, "tls.version")
+			}
+			if alpn != "" {
+				assert.Equal(t, alpn, attrs["tls.alpn"].Value.String())
+			} else {
+				assert.NotContains(t, attrs, "tls.alpn")
+			}
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		state   *tls.ConnectionState
+		version string
+		alpn    string
+	}{
+		{
+			name:    "h2",
+			state:   &tls.ConnectionState{Version: tls.VersionTLS13, NegotiatedProtocol: "h2"},
+			version: "TLS 1.3",
+			alpn:    "h2",
+		},
+		{
+			name:    "no alpn",
+			state:   &tls.ConnectionState{Version: tls.VersionTLS12},
+			version: "TLS 1.2",
+		},
+		{
+			name: "without tls",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.state, tc.version, tc.alpn))
+	}
+}
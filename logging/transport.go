@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+var _ http.RoundTripper = &Transport{}
+
+// Transport is an [http.RoundTripper] that records a log for every outbound
+// request made through the wrapped [http.RoundTripper]. It shares [Option]
+// functions with [Middleware]; options that only apply to incoming requests,
+// such as path filters, are ignored.
+type Transport struct {
+	http.RoundTripper
+
+	mw *Middleware
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rt := t.RoundTripper
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := rt.RoundTrip(r)
+
+	var status int
+	if resp != nil {
+		status = resp.StatusCode
+	}
+
+	ctx := r.Context()
+	attrs := []slog.Attr{
+		slog.Int(t.mw.keys.status, status),
+		slog.String("server.address", r.URL.Host),
+		slog.String(t.mw.keys.path, r.URL.Path),
+		slog.String(t.mw.keys.method, r.Method),
+		slog.Any(t.mw.keys.duration, time.Since(start)),
+	}
+
+	level := t.mw.leveler(status)
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		level = max(level, slog.LevelError)
+	}
+
+	for i, fn := range t.mw.extractors {
+		attrs = append(attrs, t.mw.extract(ctx, i, fn)...)
+	}
+
+	var msg string
+	if t.mw.messageFunc != nil {
+		msg = t.mw.messageFunc(attrs)
+	} else if !t.mw.noMessage {
+		msg = fmt.Sprintf("%s %s%s [%d]", r.Method, r.URL.Host, r.URL.Path, status)
+	}
+
+	t.mw.logger.LogAttrs(ctx, level, msg, attrs...)
+
+	return resp, err
+}
+
+// WrapClient replaces the [http.Client.Transport] of c with a [Transport] that
+// logs every outbound request, and returns c. A nil [http.Client.Transport] is
+// treated as [http.DefaultTransport].
+func WrapClient(c *http.Client, opts ...Option) *http.Client {
+	c.Transport = &Transport{
+		RoundTripper: c.Transport,
+		mw:           newMiddleware(nil, opts...),
+	}
+
+	return c
+}
@@ -0,0 +1,77 @@
+package logging_test
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+type testRoundTripper struct {
+	status int
+	err    error
+}
+
+func (trt *testRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if trt.err != nil {
+		return nil, trt.err
+	}
+	return &http.Response{StatusCode: trt.status, Request: r}, nil
+}
+
+func TestTransport_Logs(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+
+	client := logging.WrapClient(&http.Client{
+		Transport: &testRoundTripper{status: http.StatusNoContent},
+	}, logging.WithLogger(logger))
+
+	req, _ := http.NewRequest(http.MethodPut, "http://example.com/foo", nil)
+	_, err := client.Do(req)
+	assert.NoError(t, err)
+
+	assert.Len(t, th.records, 1)
+	assert.Equal(t, "PUT example.com/foo [204]", th.records[0].Message)
+	assert.Equal(t, slog.LevelInfo, th.records[0].Level)
+
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, int64(http.StatusNoContent), attrs["http.status_code"].Value.Int64())
+	assert.Equal(t, http.MethodPut, attrs["http.method"].Value.String())
+	assert.Equal(t, "example.com", attrs["server.address"].Value.String())
+	assert.Equal(t, "/foo", attrs["http.path"].Value.String())
+	assert.NotEqual(t, time.Duration(0), attrs["duration"].Value.Duration())
+}
+
+func TestTransport_Error(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+
+	client := logging.WrapClient(&http.Client{
+		Transport: &testRoundTripper{err: errors.New("connection refused")},
+	}, logging.WithLogger(logger))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	_, err := client.Do(req)
+	assert.Error(t, err)
+
+	assert.Len(t, th.records, 1)
+	assert.Equal(t, slog.LevelError, th.records[0].Level)
+
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.Equal(t, int64(0), attrs["http.status_code"].Value.Int64())
+	assert.Equal(t, "connection refused", attrs["error"].Value.String())
+}
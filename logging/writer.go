@@ -0,0 +1,233 @@
+package logging
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+var (
+	_ http.ResponseWriter = &wrappedWriter{}
+	_ http.Flusher        = flushWriter{}
+	_ http.Hijacker       = hijackWriter{}
+	_ http.Pusher         = pushWriter{}
+	_ http.Flusher        = flushHijackWriter{}
+	_ http.Hijacker       = flushHijackWriter{}
+	_ http.Flusher        = flushPushWriter{}
+	_ http.Pusher         = flushPushWriter{}
+	_ http.Hijacker       = hijackPushWriter{}
+	_ http.Pusher         = hijackPushWriter{}
+	_ http.Flusher        = flushHijackPushWriter{}
+	_ http.Hijacker       = flushHijackPushWriter{}
+	_ http.Pusher         = flushHijackPushWriter{}
+)
+
+type wrappedWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	pushes int
+
+	// locked is set when the handler tries to change the status after it
+	// was sent, e.g. by calling http.Error after a partial write. The
+	// logged status is the one that was sent, not the attempted one.
+	locked bool
+
+	// hijacked is set once the handler took over the connection.
+	hijacked bool
+
+	// writeErr is the first error returned by Write, e.g. when the client
+	// disconnected mid-response.
+	writeErr error
+
+	// controlHeader is the response header set by [WithLogControlHeader],
+	// and forceLog the parsed value once the header has been checked.
+	controlHeader string
+	controlled    bool
+	forceLog      *bool
+
+	// capture holds the start of the response body, if it is being
+	// captured.
+	capture *capture
+
+	// unsupported applies the [UnsupportedPolicy] when the wrapped writer
+	// reports [http.ErrNotSupported]. If nil, the call fails.
+	unsupported func(iface string) error
+}
+
+func (w *wrappedWriter) WriteHeader(code int) {
+	// Informational responses are followed by the final status.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status != 0 {
+		w.locked = true
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.checkControl()
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *wrappedWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.checkControl()
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	if err != nil && w.writeErr == nil {
+		w.writeErr = err
+	}
+	if w.capture != nil {
+		w.capture.write(data[:n])
+	}
+	return n, err
+}
+
+// checkControl reads and removes the log control header before the response
+// headers are sent. Only the first call has any effect.
+func (w *wrappedWriter) checkControl() {
+	if w.controlHeader == "" || w.controlled {
+		return
+	}
+	w.controlled = true
+
+	h := w.Header()
+	if force, err := strconv.ParseBool(h.Get(w.controlHeader)); err == nil {
+		w.forceLog = &force
+	}
+	h.Del(w.controlHeader)
+}
+
+// push initiates an HTTP/2 server push. If the wrapped writer refuses with
+// [http.ErrNotSupported], for example because the client disabled push, the
+// [UnsupportedPolicy] decides what the handler sees. The wrapped writer must
+// implement [http.Pusher].
+func (w *wrappedWriter) push(target string, opts *http.PushOptions) error {
+	err := w.ResponseWriter.(http.Pusher).Push(target, opts)
+	if errors.Is(err, http.ErrNotSupported) {
+		return w.notSupported("http.Pusher")
+	}
+	if err == nil {
+		w.pushes++
+	}
+	return err
+}
+
+// flush sends any buffered data to the client, sending the headers with a
+// 200 status first if the handler has not written them. The wrapped writer
+// must implement [http.Flusher].
+func (w *wrappedWriter) flush() {
+	if w.status == 0 {
+		w.checkControl()
+		w.status = http.StatusOK
+	}
+	w.ResponseWriter.(http.Flusher).Flush()
+}
+
+// hijack takes over the connection. Since the handler speaks to the client
+// directly from then on, the status is logged as 101 Switching Protocols
+// unless the handler wrote one before hijacking, and bytes written to the
+// connection are not counted. The wrapped writer must implement
+// [http.Hijacker].
+func (w *wrappedWriter) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return conn, rw, err
+	}
+	w.hijacked = true
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, nil
+}
+
+// flushWriter, hijackWriter, pushWriter and their combinations add the
+// optional interfaces of the wrapped writer to a [wrappedWriter].
+type (
+	flushWriter           struct{ *wrappedWriter }
+	hijackWriter          struct{ *wrappedWriter }
+	pushWriter            struct{ *wrappedWriter }
+	flushHijackWriter     struct{ *wrappedWriter }
+	flushPushWriter       struct{ *wrappedWriter }
+	hijackPushWriter      struct{ *wrappedWriter }
+	flushHijackPushWriter struct{ *wrappedWriter }
+)
+
+func (w flushWriter) Flush()           { w.flush() }
+func (w flushHijackWriter) Flush()     { w.flush() }
+func (w flushPushWriter) Flush()       { w.flush() }
+func (w flushHijackPushWriter) Flush() { w.flush() }
+
+func (w hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error)          { return w.hijack() }
+func (w flushHijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error)     { return w.hijack() }
+func (w hijackPushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error)      { return w.hijack() }
+func (w flushHijackPushWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) { return w.hijack() }
+
+func (w pushWriter) Push(t string, o *http.PushOptions) error            { return w.push(t, o) }
+func (w flushPushWriter) Push(t string, o *http.PushOptions) error       { return w.push(t, o) }
+func (w hijackPushWriter) Push(t string, o *http.PushOptions) error      { return w.push(t, o) }
+func (w flushHijackPushWriter) Push(t string, o *http.PushOptions) error { return w.push(t, o) }
+
+// writer returns the [http.ResponseWriter] to pass to the handler. Optional
+// interfaces that can't be emulated, such as [http.Flusher], [http.Hijacker]
+// and [http.Pusher], are only advertised if the wrapped writer implements
+// them, so that handlers that check for them get a truthful answer.
+func (w *wrappedWriter) writer() http.ResponseWriter {
+	_, flusher := w.ResponseWriter.(http.Flusher)
+	_, hijacker := w.ResponseWriter.(http.Hijacker)
+	_, pusher := w.ResponseWriter.(http.Pusher)
+	switch {
+	case flusher && hijacker && pusher:
+		return flushHijackPushWriter{w}
+	case flusher && hijacker:
+		return flushHijackWriter{w}
+	case flusher && pusher:
+		return flushPushWriter{w}
+	case hijacker && pusher:
+		return hijackPushWriter{w}
+	case flusher:
+		return flushWriter{w}
+	case hijacker:
+		return hijackWriter{w}
+	case pusher:
+		return pushWriter{w}
+	}
+	return w
+}
+
+func (w *wrappedWriter) notSupported(iface string) error {
+	if w.unsupported == nil {
+		return http.ErrNotSupported
+	}
+	return w.unsupported(iface)
+}
+
+// UnsupportedPolicy decides what happens when a handler uses an optional
+// interface, such as [http.Pusher], and the underlying [http.ResponseWriter]
+// reports [http.ErrNotSupported], for example because the client disabled
+// HTTP/2 server push.
+type UnsupportedPolicy int
+
+const (
+	// UnsupportedError returns [http.ErrNotSupported] to the handler. This
+	// is the default.
+	UnsupportedError UnsupportedPolicy = iota
+	// UnsupportedIgnore silently does nothing.
+	UnsupportedIgnore
+	// UnsupportedWarn does nothing but logs a warning.
+	UnsupportedWarn
+)
+
+// WithUnsupportedPolicy sets what happens when the underlying
+// [http.ResponseWriter] reports an optional interface as not supported.
+func WithUnsupportedPolicy(p UnsupportedPolicy) Option {
+	return func(mw *Middleware) {
+		mw.unsupported = p
+	}
+}
@@ -0,0 +1,492 @@
+package logging_test
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	logging "jsocol.io/middleware/logging"
+)
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, _ *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+// refusingPusher implements [http.Pusher] but refuses every push, like an
+// HTTP/2 connection whose client disabled server push.
+type refusingPusher struct {
+	*httptest.ResponseRecorder
+}
+
+func (refusingPusher) Push(string, *http.PushOptions) error {
+	return http.ErrNotSupported
+}
+
+func TestMiddleware_Push(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	var pushErr error
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		if p, ok := w.(http.Pusher); ok {
+			pushErr = p.Push("/style.css", nil)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rr := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger))
+
+	mw.ServeHTTP(rr, r)
+
+	assert.NoError(t, pushErr)
+	assert.Equal(t, []string{"/style.css"}, rr.pushed)
+
+	assert.Len(t, th.records, 1)
+	attrs := make(map[string]slog.Attr)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a
+		return true
+	})
+	assert.True(t, attrs["http.pushed"].Value.Bool())
+}
+
+func TestMiddleware_PushNotAdvertised(t *testing.T) {
+	var pusher bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		_, pusher = w.(http.Pusher)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(slog.New(&testHandler{})), logging.WithUnsupportedPolicy(logging.UnsupportedIgnore))
+
+	mw.ServeHTTP(rr, r)
+
+	assert.False(t, pusher)
+}
+
+func TestMiddleware_WithUnsupportedPolicy(t *testing.T) {
+	f := func(policy logging.UnsupportedPolicy, expectedErr error, warned bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			var pushErr error
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				if p, ok := w.(http.Pusher); ok {
+					pushErr = p.Push("/style.css", nil)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			rr := refusingPusher{httptest.NewRecorder()}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger), logging.WithUnsupportedPolicy(policy))
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Equal(t, expectedErr, pushErr)
+
+			if warned {
+				assert.Len(t, th.records, 2)
+				assert.Equal(t, slog.LevelWarn, th.records[0].Level)
+				assert.Equal(t, "response writer does not support http.Pusher", th.records[0].Message)
+			} else {
+				assert.Len(t, th.records, 1)
+			}
+			attrs := make(map[string]slog.Attr)
+			th.records[len(th.records)-1].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.NotContains(t, attrs, "http.pushed")
+		}
+	}
+
+	testCases := []struct {
+		name        string
+		policy      logging.UnsupportedPolicy
+		expectedErr error
+		warned      bool
+	}{
+		{
+			name:        "error",
+			policy:      logging.UnsupportedError,
+			expectedErr: http.ErrNotSupported,
+		},
+		{
+			name:   "ignore",
+			policy: logging.UnsupportedIgnore,
+		},
+		{
+			name:   "warn",
+			policy: logging.UnsupportedWarn,
+			warned: true,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.policy, tc.expectedErr, tc.warned))
+	}
+}
+
+func TestMiddleware_StatusLocked(t *testing.T) {
+	f := func(handler http.HandlerFunc, opts []logging.Option, status int, locked bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.Handle("/", handler)
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, append(opts, logging.WithLogger(logger))...)
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, int64(status), attrs["http.status_code"].Value.Int64())
+			if locked {
+				assert.True(t, attrs["http.status_locked"].Value.Bool())
+			} else {
+				assert.NotContains(t, attrs, "http.status_locked")
+			}
+		}
+	}
+
+	writeThenError := func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		http.Error(w, "failed", http.StatusInternalServerError)
+	}
+
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		opts    []logging.Option
+		status  int
+		locked  bool
+	}{
+		{
+			name:    "write then error",
+			handler: writeThenError,
+			status:  http.StatusOK,
+			locked:  true,
+		},
+		{
+			name:    "write then error with optional features",
+			handler: writeThenError,
+			opts:    []logging.Option{logging.WithStatusClass()},
+			status:  http.StatusOK,
+			locked:  true,
+		},
+		{
+			name: "error only",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "failed", http.StatusInternalServerError)
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "informational before final status",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusAccepted)
+			},
+			status: http.StatusAccepted,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.handler, tc.opts, tc.status, tc.locked))
+	}
+}
+
+func TestMiddleware_ResponseSize(t *testing.T) {
+	f := func(chunks []string, opts []logging.Option, key string, expected int64, logged bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				if len(chunks) == 0 {
+					w.WriteHeader(http.StatusNoContent)
+				}
+				for _, c := range chunks {
+					_, _ = w.Write([]byte(c))
+				}
+			})
+
+			rr := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, append(opts, logging.WithLogger(logger))...)
+
+			mw.ServeHTTP(rr, r)
+
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if logged {
+				assert.Equal(t, expected, attrs[key].Value.Int64())
+			} else {
+				assert.NotContains(t, attrs, "http.response_size")
+			}
+		}
+	}
+
+	payload := []string{"hello, ", "world", "!"}
+
+	testCases := []struct {
+		name     string
+		chunks   []string
+		opts     []logging.Option
+		key      string
+		expected int64
+		logged   bool
+	}{
+		{
+			name:     "multiple writes",
+			chunks:   payload,
+			key:      "http.response_size",
+			expected: int64(len("hello, world!")),
+			logged:   true,
+		},
+		{
+			name:     "multiple writes with optional features",
+			chunks:   payload,
+			opts:     []logging.Option{logging.WithStatusClass()},
+			key:      "http.response_size",
+			expected: int64(len("hello, world!")),
+			logged:   true,
+		},
+		{
+			name:   "no content",
+			key:    "http.response_size",
+			logged: true,
+		},
+		{
+			name:     "semconv key",
+			chunks:   payload,
+			opts:     []logging.Option{logging.WithOTelSemconv()},
+			key:      "http.response.body.size",
+			expected: int64(len("hello, world!")),
+			logged:   true,
+		},
+		{
+			name:   "disabled",
+			chunks: payload,
+			opts:   []logging.Option{logging.WithoutResponseSize()},
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testCases {
+		t.Run(tc.name, f(tc.chunks, tc.opts, tc.key, tc.expected, tc.logged))
+	}
+}
+
+// failingWriter is a [http.ResponseWriter] whose writes fail, as when the
+// client has disconnected.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, syscall.EPIPE
+}
+
+func TestMiddleware_WriteError(t *testing.T) {
+	f := func(opts ...logging.Option) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				for range 3 {
+					_, _ = w.Write([]byte("event\n"))
+				}
+			})
+
+			w := failingWriter{httptest.NewRecorder()}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+			mw := logging.Wrap(mux, append(opts, logging.WithLogger(logger))...)
+
+			mw.ServeHTTP(w, r)
+
+			assert.Len(t, th.records, 1)
+			assert.Equal(t, slog.LevelWarn, th.records[0].Level)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			assert.Equal(t, syscall.EPIPE.Error(), attrs["http.write_error"].Value.String())
+			assert.Equal(t, int64(0), attrs["http.response_size"].Value.Int64())
+		}
+	}
+
+	t.Run("fast path", f())
+	t.Run("general path", f(logging.WithStatusClass()))
+}
+
+// plainWriter hides the optional interfaces of the recorder.
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestMiddleware_Flush(t *testing.T) {
+	f := func(w http.ResponseWriter, rr *httptest.ResponseRecorder, flusher bool) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			var ok bool
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+				var fl http.Flusher
+				fl, ok = w.(http.Flusher)
+				_, _ = w.Write([]byte("data: 1\n\n"))
+				if ok {
+					fl.Flush()
+				}
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/events", nil)
+
+			mw := logging.Wrap(mux, logging.WithLogger(logger))
+
+			mw.ServeHTTP(w, r)
+
+			assert.Equal(t, flusher, ok)
+			assert.Equal(t, flusher, rr.Flushed)
+			assert.Equal(t, "data: 1\n\n", rr.Body.String())
+			assert.Len(t, th.records, 1)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	t.Run("flusher", f(rr, rr, true))
+
+	rr = httptest.NewRecorder()
+	t.Run("not a flusher", f(plainWriter{rr}, rr, false))
+}
+
+func TestMiddleware_FlushBeforeWrite(t *testing.T) {
+	th := &testHandler{}
+	logger := slog.New(th)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		w.(http.Flusher).Flush()
+	})
+
+	rr := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mw := logging.Wrap(mux, logging.WithLogger(logger))
+
+	mw.ServeHTTP(rr, r)
+
+	assert.True(t, rr.Flushed)
+	assert.Len(t, th.records, 1)
+	th.records[0].Attrs(func(a slog.Attr) bool {
+		if a.Key == "http.status_code" {
+			assert.Equal(t, int64(http.StatusOK), a.Value.Int64())
+		}
+		return true
+	})
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, bufio.NewReadWriter(bufio.NewReader(h.conn), bufio.NewWriter(h.conn)), nil
+}
+
+func TestMiddleware_Hijack(t *testing.T) {
+	f := func(w http.ResponseWriter, hijacker bool, opts ...logging.Option) func(*testing.T) {
+		return func(t *testing.T) {
+			th := &testHandler{}
+			logger := slog.New(th)
+			var ok bool
+			var hijackErr error
+			mux := http.NewServeMux()
+			mux.HandleFunc("/ws", func(w http.ResponseWriter, _ *http.Request) {
+				var hj http.Hijacker
+				if hj, ok = w.(http.Hijacker); !ok {
+					http.Error(w, "no upgrade", http.StatusInternalServerError)
+					return
+				}
+				var conn net.Conn
+				conn, _, hijackErr = hj.Hijack()
+				if hijackErr == nil {
+					conn.Close()
+				}
+			})
+
+			r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+
+			mw := logging.Wrap(mux, append(opts, logging.WithLogger(logger))...)
+
+			mw.ServeHTTP(w, r)
+
+			assert.Equal(t, hijacker, ok)
+			assert.NoError(t, hijackErr)
+			assert.Len(t, th.records, 1)
+			attrs := make(map[string]slog.Attr)
+			th.records[0].Attrs(func(a slog.Attr) bool {
+				attrs[a.Key] = a
+				return true
+			})
+			if hijacker {
+				assert.Equal(t, int64(http.StatusSwitchingProtocols), attrs["http.status_code"].Value.Int64())
+				assert.True(t, attrs["http.hijacked"].Value.Bool())
+			} else {
+				assert.Equal(t, int64(http.StatusInternalServerError), attrs["http.status_code"].Value.Int64())
+				assert.NotContains(t, attrs, "http.hijacked")
+			}
+		}
+	}
+
+	newHijacker := func() http.ResponseWriter {
+		server, client := net.Pipe()
+		t.Cleanup(func() { client.Close() })
+		return &hijackRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}
+	}
+
+	t.Run("hijacker", f(newHijacker(), true))
+	t.Run("hijacker with optional features", f(newHijacker(), true, logging.WithStatusClass()))
+	t.Run("not a hijacker", f(httptest.NewRecorder(), false))
+}